@@ -0,0 +1,130 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"gopkg.in/bblfsh/sdk.v1/uast"
+)
+
+type ABC struct{}
+
+// ABCData is the ABC metric of a function: its assignments, branches and
+// conditions, and the magnitude of that vector.
+type ABCData struct {
+	Name        string  `json:"name"`
+	Assignments int     `json:"assignments"`
+	Branches    int     `json:"branches"`
+	Conditions  int     `json:"conditions"`
+	Magnitude   float64 `json:"magnitude"`
+	Span
+}
+
+// ABCResult is the result of the ABC tool.
+type ABCResult struct {
+	Functions []*ABCData `json:"functions"`
+}
+
+// Analyze returns an *ABCResult with the ABC metric of every function in the
+// node.
+func (a ABC) Analyze(ctx context.Context, n *uast.Node) (Result, error) {
+	return &ABCResult{Functions: ABCMetric(n)}, nil
+}
+
+func (ad *ABCData) String() string {
+	return fmt.Sprintf("FuncName:%s, ABC:<%d,%d,%d>, Magnitude:%.2f, Position:%s\n",
+		ad.Name, ad.Assignments, ad.Branches, ad.Conditions, ad.Magnitude, ad.Span)
+}
+
+// ABCMetric computes the ABC metric of the functions in a *uast.Node, see:
+// https://en.wikipedia.org/wiki/ABC_Software_Metric
+//
+// The nodes are classified by their roles:
+// * the assignments are the nodes with the Assignment role, but their
+// operands, and the increments and decrements. The declarations of variables
+// with an initial value are not counted.
+// * the branches are the calls, which have their callee as a child.
+// * the conditions are the comparison operators, else and else if, the case
+// and default labels, catch and the conditional expressions.
+//
+// The comparison operators are told by their roles or, as some drivers don't
+// annotate them, by their token or "operator" property.
+//
+// The functions are found as in NPathComplexity, the nested functions also
+// count for the ones declaring them.
+func ABCMetric(n *uast.Node) []*ABCData {
+	var result []*ABCData
+	for _, function := range functions(n) {
+		data := &ABCData{Name: function.name, Span: function.span()}
+		iter := uast.NewOrderPathIter(uast.NewPath(function.body))
+		for {
+			p := iter.Next()
+			if p.IsEmpty() {
+				break
+			}
+
+			n := p.Node()
+			switch {
+			case isABCAssignment(n):
+				data.Assignments++
+			case halsteadCall.matches(n):
+				data.Branches++
+			case isABCCondition(n):
+				data.Conditions++
+			}
+		}
+		data.Magnitude = math.Sqrt(float64(data.Assignments*data.Assignments +
+			data.Branches*data.Branches + data.Conditions*data.Conditions))
+		result = append(result, data)
+	}
+	return result
+}
+
+func isABCAssignment(n *uast.Node) bool {
+	return containsRoles(n, []uast.Role{uast.Assignment}, []uast.Role{uast.Left, uast.Right}) ||
+		containsRoles(n, []uast.Role{uast.Operator, uast.Increment}, nil) ||
+		containsRoles(n, []uast.Role{uast.Operator, uast.Decrement}, nil)
+}
+
+// abcConditions match the conditions but the comparison operators.
+var abcConditions = []CyclomaticRule{
+	{Roles: []uast.Role{uast.If, uast.Else}, NotRoles: []uast.Role{uast.Expression}},
+	caseRule,
+	{Roles: []uast.Role{uast.Statement, uast.Switch, uast.Default}, NotRoles: []uast.Role{uast.Body}},
+	catchRule,
+}
+
+// abcComparisonRoles are the roles of the comparison operators, the negated
+// ones have also the Not role.
+var abcComparisonRoles = []uast.Role{
+	uast.Relational, uast.Equal, uast.LessThan, uast.LessThanOrEqual,
+	uast.GreaterThan, uast.GreaterThanOrEqual, uast.Identical,
+}
+
+// abcComparisonOperators are the comparison operators of the nodes without
+// their roles.
+var abcComparisonOperators = map[string]bool{
+	"==": true, "!=": true, "<": true, "<=": true, ">": true, ">=": true,
+	"===": true, "!==": true, "<>": true,
+}
+
+func isABCCondition(n *uast.Node) bool {
+	for _, rule := range abcConditions {
+		if rule.matches(n) {
+			return true
+		}
+	}
+	if isConditionalExpr(n) {
+		return true
+	}
+	if !containsRoles(n, []uast.Role{uast.Operator}, []uast.Role{uast.Assignment}) {
+		return false
+	}
+	for _, role := range abcComparisonRoles {
+		if containsRoles(n, []uast.Role{role}, nil) {
+			return true
+		}
+	}
+	return abcComparisonOperators[n.Token] || abcComparisonOperators[n.Properties["operator"]]
+}
@@ -0,0 +1,72 @@
+package tools
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/bblfsh/sdk.v1/uast"
+)
+
+func TestABCFixtures(t *testing.T) {
+	require := require.New(t)
+
+	cases := []struct {
+		file   string
+		expect [][]int
+	}{
+		// fact: the comparisons, else if and else, and the recursive call;
+		// sign: the comparisons, the conditional expressions and catch
+		{"fixtures/npath/cognitive.java.json", [][]int{{3, 0, 3}, {0, 0, 3}, {0, 1, 6}, {1, 0, 6}}},
+		{"fixtures/npath/ternary.py.json", [][]int{{0, 0, 2}, {1, 0, 4}, {0, 0, 3}}},
+	}
+
+	for _, c := range cases {
+		result, err := ABC{}.Analyze(context.Background(), fixtureUAST(t, c.file))
+		require.NoError(err)
+
+		var got [][]int
+		for _, f := range result.(*ABCResult).Functions {
+			got = append(got, []int{f.Assignments, f.Branches, f.Conditions})
+		}
+		require.Equal(c.expect, got, c.file)
+	}
+}
+
+func TestABCNodes(t *testing.T) {
+	require := require.New(t)
+
+	id := func(roles ...uast.Role) *uast.Node {
+		return &uast.Node{InternalType: "id", Roles: append([]uast.Role{uast.Expression, uast.Identifier}, roles...), Token: "x"}
+	}
+	operator := func(properties map[string]string, token string, roles ...uast.Role) *uast.Node {
+		return &uast.Node{
+			InternalType: "op",
+			Roles:        append([]uast.Role{uast.Expression, uast.Operator}, roles...),
+			Token:        token,
+			Properties:   properties,
+			Children:     []*uast.Node{id(uast.Left), id(uast.Right)},
+		}
+	}
+
+	f := ABCMetric(cognitiveBody(
+		// x = x: one assignment, not its operands
+		operator(nil, "", uast.Assignment),
+		// x++
+		&uast.Node{InternalType: "inc", Roles: []uast.Role{uast.Expression, uast.Operator, uast.Unary, uast.Increment}, Children: []*uast.Node{id()}},
+		// comparisons by role, by token and by property
+		operator(nil, "", uast.Relational, uast.GreaterThan),
+		operator(nil, "!=", uast.Binary),
+		operator(map[string]string{"operator": "<="}, "", uast.Binary),
+		// x + x is not a condition
+		operator(map[string]string{"operator": "+"}, "", uast.Binary, uast.Arithmetic),
+		// f(x)
+		&uast.Node{InternalType: "call", Roles: []uast.Role{uast.Expression, uast.Call}, Children: []*uast.Node{
+			{InternalType: "callee", Roles: []uast.Role{uast.Call, uast.Callee}, Token: "f"},
+			id(uast.Call, uast.Argument),
+		}},
+	))[0]
+
+	require.Equal([]int{2, 1, 3}, []int{f.Assignments, f.Branches, f.Conditions})
+	require.InDelta(3.742, f.Magnitude, 0.001)
+}
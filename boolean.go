@@ -0,0 +1,114 @@
+package tools
+
+import (
+	"gopkg.in/bblfsh/sdk.v1/uast"
+)
+
+// isShortCircuit reports whether n is a boolean operator evaluating its
+// operands in short-circuit, that is, an and or an or.
+func isShortCircuit(n *uast.Node) bool {
+	return containsRoles(n, []uast.Role{uast.Operator, uast.Boolean, uast.And}, nil) ||
+		containsRoles(n, []uast.Role{uast.Operator, uast.Boolean, uast.Or}, nil)
+}
+
+// shortCircuitConditions returns the number of conditions added by the
+// short-circuit boolean expressions in n, including n itself.
+func shortCircuitConditions(n *uast.Node) int {
+	count := shortCircuitIncrement(n)
+	for _, child := range n.Children {
+		count += shortCircuitConditions(child)
+	}
+	return count
+}
+
+// shortCircuitIncrement returns the number of conditions added by the
+// boolean expression n, one less than its operands. The drivers represent
+// the boolean expressions either as an operator node with its operands as
+// children, like the nested binary operators of Java or Go, or as a node
+// with the operator tokens among its operands, like the single "and" of
+// Python "a and b and c", Lisp "(and a b c)" or a flat infix expression.
+// Every operator adds at least one, even if its operands are missing.
+func shortCircuitIncrement(n *uast.Node) int {
+	if len(n.Children) == 0 {
+		// operator tokens are counted by the expression containing them
+		return 0
+	}
+
+	var operators, operands int
+	for _, child := range n.Children {
+		if len(child.Children) == 0 && isShortCircuit(child) {
+			operators++
+		} else {
+			operands++
+		}
+	}
+
+	switch {
+	case operators > 0:
+		return maxInt(operators, operands-1)
+	case isShortCircuit(n):
+		return maxInt(1, operands-1)
+	default:
+		return 0
+	}
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// isBooleanExpr reports whether n is a short-circuit boolean expression,
+// either an operator with its operands as children or a node with operator
+// tokens among them.
+func isBooleanExpr(n *uast.Node) bool {
+	return shortCircuitIncrement(n) > 0
+}
+
+// shortCircuitRole returns the role of a short-circuit operator, And or Or.
+func shortCircuitRole(n *uast.Node) uast.Role {
+	if containsRoles(n, []uast.Role{uast.And}, nil) {
+		return uast.And
+	}
+	return uast.Or
+}
+
+// booleanSequence appends to ops the short-circuit operators of the boolean
+// expression n in the order they are evaluated, including the ones nested in
+// its operands, and to operands the operands that aren't boolean expressions.
+// An operator taking several operands, like Python "a and b and c", appears
+// once between every two of them.
+func booleanSequence(n *uast.Node, ops *[]uast.Role, operands *[]*uast.Node) {
+	operator := isShortCircuit(n)
+	role := shortCircuitRole(n)
+	afterOperand := false
+	for _, child := range n.Children {
+		if len(child.Children) == 0 && isShortCircuit(child) {
+			operator, role = true, shortCircuitRole(child)
+			*ops = append(*ops, role)
+			afterOperand = false
+			continue
+		}
+
+		if operator && afterOperand {
+			*ops = append(*ops, role)
+		}
+		afterOperand = true
+		if expr := unwrapExpr(child); isBooleanExpr(expr) {
+			booleanSequence(expr, ops, operands)
+		} else {
+			*operands = append(*operands, child)
+		}
+	}
+}
+
+// unwrapExpr returns the expression wrapped by n, like the parenthesized
+// expressions of some drivers, which have it as their only child.
+func unwrapExpr(n *uast.Node) *uast.Node {
+	for len(n.Children) == 1 && !containsRoles(n, []uast.Role{uast.Operator}, nil) && !containsRoles(n, []uast.Role{uast.Call}, nil) {
+		n = n.Children[0]
+	}
+	return n
+}
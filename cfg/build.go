@@ -0,0 +1,363 @@
+package cfg
+
+import (
+	"github.com/bblfsh/tools/internal/uastutil"
+	"gopkg.in/bblfsh/sdk.v1/uast"
+)
+
+// Build returns the control flow graph of the body of a function.
+//
+// The nested function reports whether a node is a nested function or type,
+// whose statements are not part of the graph. If it's nil, they are the
+// nodes with the Function role, like lambdas, and the type declarations.
+//
+// The statements are told by their roles:
+// * if, with its condition and its Then and Else children.
+// * for and while, with their Body child, and do while, whose body comes
+// before the condition.
+// * switch, with its case and default labels. The statements of a label are
+// either its children or the ones following it, and they fall through to the
+// next label unless they break. A switch without default has an edge for
+// when no label matches.
+// * try, with its Body, Catch and Finally children. Every catch can be
+// reached from the start of the try. The finally is reached from the end of
+// the body and of the catches, while the jumps out of the try skip it.
+// * break and continue, which jump to the enclosing loop or switch, or to
+// the statement with the same label, like "continue outer".
+// * return and throw, which jump to the exit.
+//
+// Goto is a statement as any other, its target is not followed.
+func Build(body *uast.Node, nested func(n *uast.Node) bool) *Graph {
+	if nested == nil {
+		nested = isNested
+	}
+
+	g := &Graph{}
+	g.addNode(Entry, "entry", 0)
+	g.addNode(Exit, "exit", 0)
+
+	b := &builder{g: g, nested: nested}
+	exits := b.visitSequence(body.Children, []pending{{from: EntryID}})
+	b.link(exits, ExitID)
+	return g
+}
+
+func isNested(n *uast.Node) bool {
+	return uastutil.HasRoles(n, uast.Function) && !uastutil.HasAnyRole(n, uast.Argument, uast.Name) ||
+		uastutil.HasRoles(n, uast.Type, uast.Declaration) && !uastutil.HasAnyRole(n, uast.Function, uast.Variable)
+}
+
+// pending is an edge whose target is not known yet.
+type pending struct {
+	from  int
+	label string
+}
+
+// target is a statement which break or continue can jump out of.
+type target struct {
+	// label is the label of the statement, if any
+	label string
+	// loop is set for the loops, the target of continue
+	loop bool
+	// breakable is set for the loops and switches, the target of the
+	// break without a label
+	breakable bool
+	breaks    []pending
+	continues []pending
+}
+
+type builder struct {
+	g       *Graph
+	nested  func(n *uast.Node) bool
+	targets []*target
+	// label is the label of the statement being visited, set by the labeled
+	// statement enclosing it
+	label string
+}
+
+func (b *builder) link(from []pending, to int) {
+	for _, p := range from {
+		b.g.addEdge(p.from, to, p.label)
+	}
+}
+
+// node adds a node for the statement n, reached from preds.
+func (b *builder) node(kind Kind, n *uast.Node, preds []pending) int {
+	var line uint32
+	if start := uastutil.StartPosition(n); start != nil {
+		line = start.Line
+	}
+	id := b.g.addNode(kind, n.InternalType, line)
+	b.link(preds, id)
+	return id
+}
+
+func (b *builder) push(t *target) *target {
+	b.targets = append(b.targets, t)
+	return t
+}
+
+func (b *builder) pop() {
+	b.targets = b.targets[:len(b.targets)-1]
+}
+
+// visit adds the nodes of the statement n, reached from preds, and returns
+// the edges leaving it to the next statement.
+func (b *builder) visit(n *uast.Node, preds []pending) []pending {
+	label := b.label
+	b.label = ""
+
+	switch {
+	case b.nested(n):
+		return preds
+	case isIf(n):
+		return b.visitIf(n, preds)
+	case isStatementOf(n, uast.For):
+		return b.visitLoop(n, uast.For, preds, label)
+	case isStatementOf(n, uast.While):
+		return b.visitLoop(n, uast.While, preds, label)
+	case isStatementOf(n, uast.DoWhile):
+		return b.visitDoWhile(n, preds, label)
+	case uastutil.HasRoles(n, uast.Statement, uast.Switch) && !uastutil.HasAnyRole(n, uast.Case, uast.Default):
+		return b.visitSwitch(n, preds, label)
+	case isStatementOf(n, uast.Try) && !uastutil.HasAnyRole(n, uast.Catch, uast.Finally):
+		return b.visitTry(n, preds)
+	case uastutil.HasRoles(n, uast.Statement, uast.Break):
+		b.visitBreak(n, preds)
+		return nil
+	case uastutil.HasRoles(n, uast.Statement, uast.Continue):
+		b.visitContinue(n, preds)
+		return nil
+	case uastutil.HasRoles(n, uast.Statement, uast.Return), uastutil.HasRoles(n, uast.Statement, uast.Throw):
+		b.link([]pending{{from: b.node(Statement, n, preds)}}, ExitID)
+		return nil
+	case isLabeled(n):
+		return b.visitLabeled(n, preds)
+	case uastutil.HasRoles(n, uast.Statement) && !uastutil.HasRoles(n, uast.Block), !b.hasStatements(n):
+		// a simple statement, or one with a block like "synchronized"
+		id := b.node(Statement, n, preds)
+		return b.visitSequence(n.Children, []pending{{from: id}})
+	default:
+		return b.visitSequence(n.Children, preds)
+	}
+}
+
+// visitSequence visits the statements one after the other, skipping the
+// expressions and the rest of nodes without statements.
+func (b *builder) visitSequence(nodes []*uast.Node, preds []pending) []pending {
+	for _, n := range nodes {
+		if b.isStatement(n) {
+			preds = b.visit(n, preds)
+		}
+	}
+	return preds
+}
+
+func (b *builder) visitIf(n *uast.Node, preds []pending) []pending {
+	id := b.node(Condition, n, preds)
+
+	var exits []pending
+	var hasThen, hasElse bool
+	for _, child := range n.Children {
+		switch {
+		case uastutil.HasRoles(child, uast.If, uast.Then) && !uastutil.HasRoles(child, uast.Expression):
+			hasThen = true
+			exits = append(exits, b.visit(child, []pending{{id, True}})...)
+		case uastutil.HasRoles(child, uast.If, uast.Else) && !uastutil.HasRoles(child, uast.Expression):
+			hasElse = true
+			exits = append(exits, b.visit(child, []pending{{id, False}})...)
+		}
+	}
+	if !hasThen {
+		exits = append(exits, pending{id, True})
+	}
+	if !hasElse {
+		exits = append(exits, pending{id, False})
+	}
+	return exits
+}
+
+func (b *builder) visitLoop(n *uast.Node, kind uast.Role, preds []pending, label string) []pending {
+	id := b.node(Condition, n, preds)
+	t := b.push(&target{label: label, loop: true, breakable: true})
+	exits := []pending{{id, True}}
+	if body := childWithRoles(n, kind, uast.Body); body != nil {
+		exits = b.visit(body, exits)
+	}
+	b.pop()
+
+	b.link(exits, id)
+	b.link(t.continues, id)
+	return append([]pending{{id, False}}, t.breaks...)
+}
+
+func (b *builder) visitDoWhile(n *uast.Node, preds []pending, label string) []pending {
+	// the condition comes after the body, so its node too, and the body
+	// starts with the first node added for it
+	first := len(b.g.Nodes)
+	t := b.push(&target{label: label, loop: true, breakable: true})
+	exits := preds
+	if body := childWithRoles(n, uast.DoWhile, uast.Body); body != nil {
+		exits = b.visit(body, exits)
+	}
+	b.pop()
+
+	id := b.node(Condition, n, exits)
+	b.link(t.continues, id)
+	start := id
+	if first < id {
+		start = first
+	}
+	b.g.addEdge(id, start, True)
+	return append([]pending{{id, False}}, t.breaks...)
+}
+
+func (b *builder) visitSwitch(n *uast.Node, preds []pending, label string) []pending {
+	id := b.node(Switch, n, preds)
+	t := b.push(&target{label: label, breakable: true})
+
+	// the exits of the previous label, falling through
+	var current []pending
+	var started, hasDefault bool
+	for _, child := range uastutil.SwitchChildren(n) {
+		switch {
+		case uastutil.IsSwitchLabel(child):
+			started = true
+			edge := CaseMatch
+			if uastutil.HasRoles(child, uast.Default) {
+				edge = Default
+				hasDefault = true
+			}
+			c := b.node(Case, child, current)
+			b.g.addEdge(id, c, edge)
+			current = b.visitSequence(child.Children, []pending{{from: c}})
+		case started && b.isStatement(child):
+			current = b.visit(child, current)
+		}
+	}
+	b.pop()
+
+	exits := append(current, t.breaks...)
+	if !hasDefault {
+		exits = append(exits, pending{id, Default})
+	}
+	return exits
+}
+
+func (b *builder) visitTry(n *uast.Node, preds []pending) []pending {
+	id := b.node(Try, n, preds)
+
+	exits := []pending{{from: id}}
+	if body := childWithRoles(n, uast.Try, uast.Body); body != nil {
+		exits = b.visit(body, exits)
+	}
+	for _, child := range n.Children {
+		if uastutil.HasRoles(child, uast.Try, uast.Catch) && !uastutil.HasRoles(child, uast.Body) {
+			c := b.node(Catch, child, nil)
+			b.g.addEdge(id, c, Exception)
+			exits = append(exits, b.visitSequence(child.Children, []pending{{from: c}})...)
+		}
+	}
+	if finally := childWithRoles(n, uast.Try, uast.Finally); finally != nil {
+		exits = b.visit(finally, exits)
+	}
+	return exits
+}
+
+func (b *builder) visitBreak(n *uast.Node, preds []pending) {
+	id := b.node(Statement, n, preds)
+	label := jumpLabel(n)
+	for i := len(b.targets) - 1; i >= 0; i-- {
+		t := b.targets[i]
+		if (label == "" && t.breakable) || (label != "" && t.label == label) {
+			t.breaks = append(t.breaks, pending{from: id})
+			return
+		}
+	}
+	// a break out of nowhere ends the function
+	b.link([]pending{{from: id}}, ExitID)
+}
+
+func (b *builder) visitContinue(n *uast.Node, preds []pending) {
+	id := b.node(Statement, n, preds)
+	label := jumpLabel(n)
+	for i := len(b.targets) - 1; i >= 0; i-- {
+		t := b.targets[i]
+		if t.loop && (label == "" || t.label == label) {
+			t.continues = append(t.continues, pending{from: id})
+			return
+		}
+	}
+	b.link([]pending{{from: id}}, ExitID)
+}
+
+// visitLabeled visits the statement of a labeled statement, which can be
+// the target of a break with its label even if it's not a loop.
+func (b *builder) visitLabeled(n *uast.Node, preds []pending) []pending {
+	label := n.Children[0].Token
+	t := b.push(&target{label: label})
+	b.label = label
+	exits := b.visitSequence(n.Children[1:], preds)
+	b.label = ""
+	b.pop()
+	return append(exits, t.breaks...)
+}
+
+// isStatement reports whether n is a statement, or has statements under it,
+// which are not in nested functions.
+func (b *builder) isStatement(n *uast.Node) bool {
+	if b.nested(n) {
+		return false
+	}
+	return uastutil.HasRoles(n, uast.Statement) || b.hasStatements(n)
+}
+
+func (b *builder) hasStatements(n *uast.Node) bool {
+	for _, child := range n.Children {
+		if b.isStatement(child) {
+			return true
+		}
+	}
+	return false
+}
+
+func isIf(n *uast.Node) bool {
+	return uastutil.HasRoles(n, uast.Statement, uast.If) && childWithRoles(n, uast.If, uast.Condition) != nil
+}
+
+// isStatementOf reports whether n is a statement of the kind, like a loop,
+// and not one of its parts annotated with the kind, like the body of a
+// loop. The statement can be the body of another one, but then it has its
+// own body or condition.
+func isStatementOf(n *uast.Node, kind uast.Role) bool {
+	return uastutil.HasRoles(n, uast.Statement, kind) && (!uastutil.HasRoles(n, uast.Body) ||
+		childWithRoles(n, kind, uast.Body) != nil || childWithRoles(n, kind, uast.Condition) != nil)
+}
+
+// isLabeled reports whether n is a labeled statement, like the Java ones: a
+// statement with the label as its first child and a statement as the
+// second one.
+func isLabeled(n *uast.Node) bool {
+	return uastutil.HasRoles(n, uast.Statement) && len(n.Children) == 2 &&
+		uastutil.HasRoles(n.Children[0], uast.Identifier) && n.Children[0].Token != "" &&
+		uastutil.HasRoles(n.Children[1], uast.Statement)
+}
+
+// jumpLabel returns the label of a break or continue, empty if it has none.
+func jumpLabel(n *uast.Node) string {
+	for _, child := range n.Children {
+		if uastutil.HasRoles(child, uast.Identifier) && child.Token != "" {
+			return child.Token
+		}
+	}
+	return ""
+}
+
+func childWithRoles(n *uast.Node, roles ...uast.Role) *uast.Node {
+	for _, child := range n.Children {
+		if uastutil.HasRoles(child, roles...) {
+			return child
+		}
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+// Package cfg builds the control flow graph of a function from its UAST,
+// telling apart the statements by their roles as the rest of the tools do.
+//
+// The graph has a node for every statement, plus an entry and an exit node.
+// The conditional statements, like if, loops and switch, branch from their
+// node, while break, continue, return and throw jump to their targets. The
+// expressions are not split, so the short-circuit boolean operators and the
+// conditional expressions don't branch.
+package cfg
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Kind is the kind of a node of the graph.
+type Kind string
+
+const (
+	// Entry is the node where the function starts.
+	Entry Kind = "entry"
+	// Exit is the node where the function ends, by returning, throwing or
+	// reaching the end of its body.
+	Exit Kind = "exit"
+	// Statement is a statement which doesn't branch, but jumps.
+	Statement Kind = "statement"
+	// Condition is the condition of an if or a loop, with an edge for when
+	// it's true and one for when it's false.
+	Condition Kind = "condition"
+	// Switch is a switch statement, with an edge to each case.
+	Switch Kind = "switch"
+	// Case is a case or default label of a switch.
+	Case Kind = "case"
+	// Try is a try statement, with an edge to its body and one to each
+	// catch.
+	Try Kind = "try"
+	// Catch is a catch clause of a try statement.
+	Catch Kind = "catch"
+)
+
+// Labels of the branching edges. CaseMatch and Default label the edges from
+// a switch to its case and default labels.
+const (
+	True      = "true"
+	False     = "false"
+	CaseMatch = "case"
+	Default   = "default"
+	Exception = "exception"
+)
+
+// Node is a node of a control flow graph.
+type Node struct {
+	ID   int  `json:"id"`
+	Kind Kind `json:"kind"`
+	// Label is the internal type of the statement.
+	Label string `json:"label"`
+	// Line is the line where the statement starts, zero if unknown.
+	Line uint32 `json:"line,omitempty"`
+}
+
+// Edge is a possible transfer of control between two nodes.
+type Edge struct {
+	From int `json:"from"`
+	To   int `json:"to"`
+	// Label tells apart the edges leaving a branching node, like "true" and
+	// "false", it's empty for the other ones.
+	Label string `json:"label,omitempty"`
+}
+
+// Graph is the control flow graph of a function. The nodes are in the order
+// they are found, the entry and exit nodes being the first two.
+type Graph struct {
+	Nodes []*Node `json:"nodes"`
+	Edges []*Edge `json:"edges"`
+}
+
+// EntryID and ExitID are the identifiers of the entry and exit nodes.
+const (
+	EntryID = 0
+	ExitID  = 1
+)
+
+func (g *Graph) addNode(kind Kind, label string, line uint32) int {
+	id := len(g.Nodes)
+	g.Nodes = append(g.Nodes, &Node{ID: id, Kind: kind, Label: label, Line: line})
+	return id
+}
+
+func (g *Graph) addEdge(from, to int, label string) {
+	g.Edges = append(g.Edges, &Edge{From: from, To: to, Label: label})
+}
+
+// Components returns the number of connected components of the graph, not
+// taking into account the direction of the edges.
+func (g *Graph) Components() int {
+	parent := make([]int, len(g.Nodes))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	for _, e := range g.Edges {
+		parent[find(e.From)] = find(e.To)
+	}
+
+	var components int
+	for i := range parent {
+		if find(i) == i {
+			components++
+		}
+	}
+	return components
+}
+
+// Cyclomatic returns the cyclomatic complexity of the graph as defined by
+// McCabe: E - N + 2P, with E edges, N nodes and P connected components.
+func (g *Graph) Cyclomatic() int {
+	return len(g.Edges) - len(g.Nodes) + 2*g.Components()
+}
+
+// WriteDOT writes the graph in the Graphviz DOT language, named after name.
+func (g *Graph) WriteDOT(w io.Writer, name string) error {
+	if _, err := fmt.Fprintf(w, "digraph %s {\n", strconv.Quote(name)); err != nil {
+		return err
+	}
+	for _, n := range g.Nodes {
+		label := n.Label
+		if n.Line > 0 {
+			label = fmt.Sprintf("%s\nL%d", label, n.Line)
+		}
+		if _, err := fmt.Fprintf(w, "\tn%d [label=%s, shape=%s];\n", n.ID, strconv.Quote(label), shapes[n.Kind]); err != nil {
+			return err
+		}
+	}
+	for _, e := range g.Edges {
+		var attrs string
+		if e.Label != "" {
+			attrs = fmt.Sprintf(" [label=%s]", strconv.Quote(e.Label))
+		}
+		if _, err := fmt.Fprintf(w, "\tn%d -> n%d%s;\n", e.From, e.To, attrs); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintln(w, "}")
+	return err
+}
+
+// shapes are the DOT shapes of the nodes by their kind.
+var shapes = map[Kind]string{
+	Entry:     "oval",
+	Exit:      "oval",
+	Statement: "box",
+	Condition: "diamond",
+	Switch:    "diamond",
+	Case:      "box",
+	Try:       "hexagon",
+	Catch:     "box",
+}
@@ -0,0 +1,227 @@
+package cfg
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/bblfsh/sdk.v1/uast"
+)
+
+func node(internalType string, roles []uast.Role, children ...*uast.Node) *uast.Node {
+	return &uast.Node{InternalType: internalType, Roles: roles, Children: children}
+}
+
+func statement(internalType string, roles ...uast.Role) *uast.Node {
+	return node(internalType, append([]uast.Role{uast.Statement}, roles...))
+}
+
+func condition(roles ...uast.Role) *uast.Node {
+	return node("cond", append([]uast.Role{uast.Expression}, roles...))
+}
+
+func label(roles ...uast.Role) *uast.Node {
+	return &uast.Node{InternalType: "label", Roles: append([]uast.Role{uast.Expression, uast.Identifier}, roles...), Token: "outer"}
+}
+
+func body(statements ...*uast.Node) *uast.Node {
+	return node("body", []uast.Role{uast.Function, uast.Body}, statements...)
+}
+
+// edges returns the edges of the graph as "from->to" or "from->to:label",
+// with the nodes by their labels.
+func edges(g *Graph) []string {
+	var edges []string
+	for _, e := range g.Edges {
+		edge := g.Nodes[e.From].Label + "->" + g.Nodes[e.To].Label
+		if e.Label != "" {
+			edge += ":" + e.Label
+		}
+		edges = append(edges, edge)
+	}
+	return edges
+}
+
+func TestBuildIf(t *testing.T) {
+	require := require.New(t)
+
+	g := Build(body(
+		node("if", []uast.Role{uast.Statement, uast.If},
+			condition(uast.If, uast.Condition),
+			node("then", []uast.Role{uast.If, uast.Then, uast.Statement, uast.Block}, statement("a")),
+		),
+		node("if2", []uast.Role{uast.Statement, uast.If},
+			condition(uast.If, uast.Condition),
+			statement("return", uast.If, uast.Then, uast.Return),
+			statement("b", uast.If, uast.Else),
+		),
+	), nil)
+
+	require.Equal([]string{
+		"entry->if",
+		"if->a:true",
+		"a->if2", "if->if2:false",
+		"if2->return:true", "return->exit",
+		"if2->b:false",
+		"b->exit",
+	}, edges(g))
+	require.Equal(3, g.Cyclomatic())
+}
+
+func TestBuildLoops(t *testing.T) {
+	require := require.New(t)
+
+	g := Build(body(
+		node("labeled", []uast.Role{uast.Statement},
+			label(),
+			node("for", []uast.Role{uast.Statement, uast.For},
+				condition(uast.For, uast.Expression),
+				node("while", []uast.Role{uast.For, uast.Body, uast.Statement, uast.While},
+					condition(uast.While, uast.Condition),
+					node("block", []uast.Role{uast.While, uast.Body, uast.Statement, uast.Block},
+						node("continue", []uast.Role{uast.Statement, uast.Continue}, label()),
+						statement("break", uast.Break),
+					),
+				),
+			),
+		),
+		node("do", []uast.Role{uast.Statement, uast.DoWhile},
+			node("block", []uast.Role{uast.DoWhile, uast.Body, uast.Statement, uast.Block}, statement("a")),
+			condition(uast.DoWhile, uast.Condition),
+		),
+	), nil)
+
+	require.Equal([]string{
+		"entry->for",
+		"for->while:true",
+		"while->continue:true",
+		"while->for:false", "break->for",
+		"continue->for",
+		"for->a:false",
+		"a->do",
+		"do->a:true",
+		"do->exit:false",
+	}, edges(g))
+	require.Equal(4, g.Cyclomatic())
+}
+
+func TestBuildSwitch(t *testing.T) {
+	require := require.New(t)
+
+	caseLabel := func(roles ...uast.Role) *uast.Node {
+		return node("case", append([]uast.Role{uast.Statement, uast.Switch}, roles...))
+	}
+	g := Build(body(
+		node("switch", []uast.Role{uast.Statement, uast.Switch},
+			condition(uast.Switch),
+			caseLabel(uast.Case),
+			statement("a", uast.Switch, uast.Case, uast.Body),
+			statement("break", uast.Break),
+			caseLabel(uast.Case),
+			statement("b", uast.Switch, uast.Case, uast.Body),
+			caseLabel(uast.Default),
+			statement("c", uast.Switch, uast.Case, uast.Body),
+		),
+	), nil)
+
+	require.Equal([]string{
+		"entry->switch",
+		"switch->case:case", "case->a", "a->break",
+		"switch->case:case", "case->b",
+		"b->case", "switch->case:default", "case->c",
+		"c->exit", "break->exit",
+	}, edges(g))
+	require.Equal(3, g.Cyclomatic())
+
+	// without a default, no label may match
+	g = Build(body(
+		node("switch", []uast.Role{uast.Statement, uast.Switch},
+			condition(uast.Switch),
+			caseLabel(uast.Case),
+			statement("a", uast.Switch, uast.Case, uast.Body),
+		),
+	), nil)
+	require.Equal([]string{
+		"entry->switch",
+		"switch->case:case", "case->a",
+		"a->exit", "switch->exit:default",
+	}, edges(g))
+	require.Equal(2, g.Cyclomatic())
+}
+
+func TestBuildTry(t *testing.T) {
+	require := require.New(t)
+
+	g := Build(body(
+		node("try", []uast.Role{uast.Statement, uast.Try},
+			node("block", []uast.Role{uast.Try, uast.Body, uast.Statement, uast.Block}, statement("a")),
+			node("catch", []uast.Role{uast.Try, uast.Catch}, statement("b")),
+			node("catch2", []uast.Role{uast.Try, uast.Catch}, statement("throw", uast.Throw)),
+			node("finally", []uast.Role{uast.Try, uast.Finally, uast.Statement, uast.Block}, statement("c")),
+		),
+	), nil)
+
+	require.Equal([]string{
+		"entry->try",
+		"try->a",
+		"try->catch:exception", "catch->b",
+		"try->catch2:exception", "catch2->throw", "throw->exit",
+		"a->c", "b->c",
+		"c->exit",
+	}, edges(g))
+	require.Equal(3, g.Cyclomatic())
+}
+
+func TestBuildNested(t *testing.T) {
+	require := require.New(t)
+
+	lambda := node("lambda", []uast.Role{uast.Expression, uast.Function, uast.Anonymous},
+		body(statement("return", uast.Return)),
+	)
+	g := Build(body(node("a", []uast.Role{uast.Statement}, lambda)), nil)
+	require.Equal([]string{"entry->a", "a->exit"}, edges(g))
+
+	// the nested functions are told by the given function
+	g = Build(body(node("a", []uast.Role{uast.Statement}, lambda)), func(n *uast.Node) bool {
+		return false
+	})
+	require.Equal([]string{"entry->a", "a->return", "return->exit"}, edges(g))
+}
+
+func TestGraphCyclomatic(t *testing.T) {
+	require := require.New(t)
+
+	g := &Graph{}
+	g.addNode(Entry, "entry", 0)
+	g.addNode(Exit, "exit", 0)
+	require.Equal(2, g.Components())
+	require.Equal(2, g.Cyclomatic())
+
+	g.addEdge(EntryID, ExitID, "")
+	require.Equal(1, g.Components())
+	require.Equal(1, g.Cyclomatic())
+}
+
+func TestGraphWriteDOT(t *testing.T) {
+	require := require.New(t)
+
+	g := &Graph{}
+	g.addNode(Entry, "entry", 0)
+	g.addNode(Exit, "exit", 0)
+	g.addNode(Condition, "if", 3)
+	g.addEdge(EntryID, 2, "")
+	g.addEdge(2, ExitID, True)
+	g.addEdge(2, ExitID, False)
+
+	var buf bytes.Buffer
+	require.NoError(g.WriteDOT(&buf, `A.f("x")`))
+	require.Equal(`digraph "A.f(\"x\")" {
+	n0 [label="entry", shape=oval];
+	n1 [label="exit", shape=oval];
+	n2 [label="if\nL3", shape=diamond];
+	n0 -> n2;
+	n2 -> n1 [label="true"];
+	n2 -> n1 [label="false"];
+}
+`, buf.String())
+}
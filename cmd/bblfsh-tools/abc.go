@@ -0,0 +1,11 @@
+package main
+
+import "github.com/bblfsh/tools"
+
+type ABC struct {
+	Common
+}
+
+func (c *ABC) Execute(args []string) error {
+	return c.execute(args, tools.ABC{}, nil)
+}
@@ -0,0 +1,18 @@
+package main
+
+import (
+	"os"
+
+	"github.com/bblfsh/tools"
+)
+
+type ControlFlow struct {
+	Common
+}
+
+func (c *ControlFlow) Execute(args []string) error {
+	// only the graphs go to the standard output, so it's valid DOT even
+	// with several files
+	c.textHeaders = os.Stderr
+	return c.execute(args, tools.ControlFlow{}, nil)
+}
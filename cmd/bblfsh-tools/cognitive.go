@@ -0,0 +1,20 @@
+package main
+
+import "github.com/bblfsh/tools"
+
+type Cognitive struct {
+	Common
+	MaxCognitive int `long:"max-cognitive" description:"maximum cognitive complexity of a function, exceeding it makes the command fail"`
+}
+
+func (c *Cognitive) Execute(args []string) error {
+	return c.execute(args, tools.Cognitive{}, c.check)
+}
+
+func (c *Cognitive) check(result tools.Result) []*violation {
+	var l limits
+	for i, f := range result.(*tools.CognitiveResult).Functions {
+		l.check("cognitive complexity", c.MaxCognitive, f.Complexity, i, f.Name, f.Span)
+	}
+	return l
+}
@@ -2,9 +2,15 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"io"
 	"io/ioutil"
+	"os"
+	"os/signal"
 	"path/filepath"
 	"strings"
+	"syscall"
 
 	"github.com/bblfsh/tools"
 
@@ -18,59 +24,314 @@ import (
 var (
 	ErrParserFatal = errors.NewKind("Fatal response from parser: %s")
 	ErrParserError = errors.NewKind("Error response from parser: %s")
+	ErrInvalidUAST = errors.NewKind("Invalid UAST input %s: %s")
+	ErrNoFiles     = errors.NewKind("No files to analyze")
+	ErrFilesFailed = errors.NewKind("%d of %d files failed")
+	ErrInterrupted = errors.NewKind("Interrupted")
+	ErrLimits      = errors.NewKind("%d metric limit(s) exceeded")
 )
 
+// stdinFile is the file name used to read the input from the standard input.
+const stdinFile = "-"
+
 type Common struct {
-	Address  string `long:"address" description:"server adress to connect to" default:"localhost:9432"`
-	Language string `long:"language" description:"language of the input" default:""`
-	Args     struct {
-		File string `positional-arg-name:"file" required:"true"`
+	Address     string   `long:"address" description:"server adress to connect to" default:"localhost:9432"`
+	Language    string   `long:"language" description:"language of the input" default:""`
+	UASTInput   bool     `long:"uast-input" description:"read the input as a UAST in JSON format (a parse response or a bare node) instead of parsing it with the server, use - as file to read from stdin"`
+	Include     []string `long:"include" description:"only analyze the files whose path matches this pattern, relative to the walked directory or the root of the glob, or whose name matches it if it has no slash, can be repeated"`
+	Exclude     []string `long:"exclude" description:"skip the files and directories matching this pattern, can be repeated"`
+	NoGitignore bool     `long:"no-gitignore" description:"do not skip the files ignored by .gitignore files"`
+	Workers     int      `long:"workers" description:"number of files parsed concurrently, defaults to the number of CPUs"`
+	Format      string   `long:"format" description:"output format" choice:"text" choice:"json" choice:"ndjson" choice:"csv" default:"text"`
+	Args        struct {
+		Files []string `positional-arg-name:"file" description:"files, directories or glob patterns to analyze" required:"1"`
 	} `positional-args:"yes"`
+
+	// textHeaders is where the text output writes the file names and the
+	// summary, the standard output if nil.
+	textHeaders io.Writer
 }
 
-func (c *Common) execute(args []string, tool tools.Tooler) error {
+// summary holds the totals of a run over several files.
+type summary struct {
+	Files      int `json:"files"`
+	Failed     int `json:"failed"`
+	Violations int `json:"violations"`
+}
+
+func (s *summary) String() string {
+	return fmt.Sprintf("Files analyzed: %d, failed: %d, limits exceeded: %d", s.Files, s.Failed, s.Violations)
+}
+
+// execute runs the tool on the input files, writing the results to the
+// standard output. If check is not nil, it's called with every result to
+// find the metric limits exceeded. The results of a tools.Aggregator are
+// written once all the files are analyzed and aggregated.
+func (c *Common) execute(args []string, tool tools.Analyzer, check checkFunc) error {
 	logrus.Debugf("executing command")
 
-	request, err := c.buildRequest()
+	files, err := c.inputFiles()
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return ErrNoFiles.New()
+	}
+
+	ctx, cancel := interruptible(context.Background())
+	defer cancel()
+
+	_, withSource := tool.(tools.SourceAnalyzer)
+	load, closeLoad, err := c.loader(withSource)
 	if err != nil {
 		return err
 	}
+	defer closeLoad()
 
-	uast, err := c.parseRequest(request)
+	out, err := newOutput(os.Stdout, c.textHeaders, c.Format, len(files) > 1)
 	if err != nil {
 		return err
 	}
 
-	return tool.Exec(uast)
+	sum := &summary{Files: len(files)}
+	write := func(result *fileResult) error {
+		if result.Result != nil && check != nil {
+			result.Violations = check(result.Result)
+			sum.Violations += len(result.Violations)
+		}
+		return out.write(result)
+	}
+
+	aggregator, _ := tool.(tools.Aggregator)
+	var pending []*fileResult
+	for p := range c.loadFiles(ctx, files, load) {
+		if ctx.Err() != nil {
+			break
+		}
+
+		result := &fileResult{File: p.file, Language: c.languageOf(p.file)}
+		err := p.err
+		if err == nil {
+			result.Result, err = analyze(ctx, tool, p)
+		}
+		if err != nil {
+			if len(files) == 1 {
+				return err
+			}
+			logrus.Errorf("error analyzing %s: %s", p.file, err)
+			result.Error = err.Error()
+			sum.Failed++
+		}
+
+		if aggregator != nil {
+			pending = append(pending, result)
+			continue
+		}
+		if err := write(result); err != nil {
+			return err
+		}
+	}
+
+	if ctx.Err() != nil {
+		return ErrInterrupted.New()
+	}
+	if aggregator != nil {
+		if err := aggregate(ctx, aggregator, pending); err != nil {
+			return err
+		}
+		for _, result := range pending {
+			if err := write(result); err != nil {
+				return err
+			}
+		}
+	}
+	if err := out.close(sum); err != nil {
+		return err
+	}
+	if sum.Failed > 0 {
+		return ErrFilesFailed.New(sum.Failed, sum.Files)
+	}
+	if sum.Violations > 0 {
+		return ErrLimits.New(sum.Violations)
+	}
+	return nil
+}
+
+// analyze runs the tool on a loaded file, with its source code if the tool
+// can use it and it's known.
+func analyze(ctx context.Context, tool tools.Analyzer, p *parsed) (tools.Result, error) {
+	if sourceTool, ok := tool.(tools.SourceAnalyzer); ok && p.source != "" {
+		return sourceTool.AnalyzeSource(ctx, p.uast, p.source)
+	}
+	return tool.Analyze(ctx, p.uast)
+}
+
+// aggregate calls the aggregator with the results of the files analyzed
+// without errors.
+func aggregate(ctx context.Context, aggregator tools.Aggregator, results []*fileResult) error {
+	var analyzed []tools.Result
+	for _, r := range results {
+		if r.Result != nil {
+			analyzed = append(analyzed, r.Result)
+		}
+	}
+	return aggregator.Aggregate(ctx, analyzed)
+}
+
+// languageOf returns the language given with --language or, if missing, the
+// one guessed from the file extension.
+func (c *Common) languageOf(file string) string {
+	if c.Language != "" {
+		return c.Language
+	}
+	return languageOf(file)
+}
+
+// interruptible returns a context which is cancelled on SIGINT or SIGTERM.
+func interruptible(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(parent)
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		defer signal.Stop(interrupt)
+		select {
+		case <-interrupt:
+			logrus.Warnf("interrupted, stopping")
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
 }
 
-func (c *Common) buildRequest() (*protocol.ParseRequest, error) {
-	logrus.Debugf("reading file %s", c.Args.File)
-	content, err := ioutil.ReadFile(c.Args.File)
+// loader returns the function used to load the UAST of each file and a
+// function to release its resources. All the files are parsed through a
+// single connection to the server. The source of the UAST files is only read
+// if withSource is set, since the parsed files always have it.
+func (c *Common) loader(withSource bool) (loadFunc, func(), error) {
+	if c.UASTInput {
+		load := func(ctx context.Context, file string) (*uast.Node, string, error) {
+			node, err := c.readUAST(ctx, file)
+			if err != nil || !withSource {
+				return node, "", err
+			}
+			return node, readSource(file), nil
+		}
+		return load, func() {}, nil
+	}
+
+	logrus.Debugf("dialing server at %s", c.Address)
+	connection, err := grpc.Dial(c.Address, grpc.WithInsecure())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	client := protocol.NewProtocolServiceClient(connection)
+	load := func(ctx context.Context, file string) (*uast.Node, string, error) {
+		request, err := c.buildRequest(file)
+		if err != nil {
+			return nil, "", err
+		}
+		node, err := c.parseRequest(ctx, client, request)
+		return node, request.Content, err
+	}
+	return load, func() { connection.Close() }, nil
+}
+
+func (c *Common) buildRequest(file string) (*protocol.ParseRequest, error) {
+	logrus.Debugf("reading file %s", file)
+	content, err := ioutil.ReadFile(file)
 	if err != nil {
 		return nil, err
 	}
 
 	request := &protocol.ParseRequest{
-		Filename: filepath.Base(c.Args.File),
+		Filename: filepath.Base(file),
 		Language: c.Language,
 		Content:  string(content),
 	}
 	return request, nil
 }
 
-func (c *Common) parseRequest(request *protocol.ParseRequest) (*uast.Node, error) {
-	logrus.Debugf("dialing request at %s", c.Address)
-	connection, err := grpc.Dial(c.Address, grpc.WithInsecure())
+func (c *Common) parseRequest(ctx context.Context, client protocol.ProtocolServiceClient, request *protocol.ParseRequest) (*uast.Node, error) {
+	logrus.Debugf("parsing file %s", request.Filename)
+	response, err := client.Parse(ctx, request)
 	if err != nil {
 		return nil, err
 	}
+	return responseUAST(response)
+}
 
-	client := protocol.NewProtocolServiceClient(connection)
-	response, err := client.Parse(context.TODO(), request)
+func (c *Common) readUAST(ctx context.Context, file string) (*uast.Node, error) {
+	if file == stdinFile {
+		logrus.Debugf("reading UAST from stdin")
+		return decodeUAST(os.Stdin, "from stdin")
+	}
+
+	logrus.Debugf("reading UAST from %s", file)
+	f, err := os.Open(file)
 	if err != nil {
 		return nil, err
 	}
+	defer f.Close()
+
+	return decodeUAST(f, file)
+}
+
+// readSource returns the source code a UAST file was parsed from, the file
+// with the same name without the .json extension, like the fixtures. It's
+// empty if the file is not found, then the tools only use the UAST.
+func readSource(file string) string {
+	if file == stdinFile || !strings.HasSuffix(file, ".json") {
+		return ""
+	}
+
+	content, err := ioutil.ReadFile(strings.TrimSuffix(file, ".json"))
+	if err != nil {
+		logrus.Warnf("source of %s not found, using only its UAST: %s", file, err)
+		return ""
+	}
+	return string(content)
+}
+
+// decodeUAST reads a JSON document which is either a protocol.ParseResponse,
+// as the ones stored in the fixtures, or a bare uast.Node.
+func decodeUAST(r io.Reader, name string) (*uast.Node, error) {
+	var raw json.RawMessage
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, ErrInvalidUAST.New(name, err)
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, ErrInvalidUAST.New(name, err)
+	}
+
+	_, hasUAST := fields["uast"]
+	_, hasStatus := fields["status"]
+	if hasUAST || hasStatus {
+		response := &protocol.ParseResponse{}
+		if err := json.Unmarshal(raw, response); err != nil {
+			return nil, ErrInvalidUAST.New(name, err)
+		}
+		if response.Status == protocol.Ok && response.UAST == nil {
+			return nil, ErrInvalidUAST.New(name, "parse response without UAST")
+		}
+		return responseUAST(response)
+	}
+
+	node := &uast.Node{}
+	if err := json.Unmarshal(raw, node); err != nil {
+		return nil, ErrInvalidUAST.New(name, err)
+	}
+	// any other JSON object decodes as an empty node
+	if node.InternalType == "" && len(node.Roles) == 0 && len(node.Children) == 0 {
+		return nil, ErrInvalidUAST.New(name, "neither a parse response nor a UAST node")
+	}
+	return node, nil
+}
+
+func responseUAST(response *protocol.ParseResponse) (*uast.Node, error) {
 	switch response.Status {
 	case protocol.Fatal:
 		return nil, ErrParserFatal.New(strings.Join(response.Errors, "\n"))
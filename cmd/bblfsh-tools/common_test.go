@@ -0,0 +1,65 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeUASTParseResponse(t *testing.T) {
+	require := require.New(t)
+
+	f, err := os.Open("../../fixtures/npath/while.java.json")
+	require.NoError(err)
+	defer f.Close()
+
+	n, err := decodeUAST(f, "while.java.json")
+	require.NoError(err)
+	require.Equal("CompilationUnit", n.InternalType)
+
+	n, err = decodeUAST(strings.NewReader(`{"status": 0, "uast": {"InternalType": "File"}}`), "ok")
+	require.NoError(err)
+	require.Equal("File", n.InternalType)
+
+	_, err = decodeUAST(strings.NewReader(`{"status": 1, "errors": ["syntax error"]}`), "error")
+	require.True(ErrParserError.Is(err))
+	require.Contains(err.Error(), "syntax error")
+
+	_, err = decodeUAST(strings.NewReader(`{"status": 2, "errors": ["driver crashed"]}`), "fatal")
+	require.True(ErrParserFatal.Is(err))
+
+	_, err = decodeUAST(strings.NewReader(`{"status": 0}`), "without uast")
+	require.True(ErrInvalidUAST.Is(err))
+}
+
+func TestDecodeUASTBareNode(t *testing.T) {
+	require := require.New(t)
+
+	n, err := decodeUAST(strings.NewReader(`{
+		"InternalType": "Module",
+		"Children": [{"InternalType": "Pass"}]
+	}`), "node")
+	require.NoError(err)
+	require.Equal("Module", n.InternalType)
+	require.Len(n.Children, 1)
+
+	// a node with just roles or children is still a node
+	_, err = decodeUAST(strings.NewReader(`{"Children": [{"InternalType": "Pass"}]}`), "children")
+	require.NoError(err)
+
+	invalid := []string{
+		``,
+		`{`,
+		`[]`,
+		`"uast"`,
+		`{}`,
+		`{"name": "package", "version": "1.0.0", "dependencies": {}}`,
+		`{"InternalType": 42}`,
+	}
+	for _, input := range invalid {
+		_, err := decodeUAST(strings.NewReader(input), "invalid")
+		require.True(ErrInvalidUAST.Is(err), input)
+	}
+}
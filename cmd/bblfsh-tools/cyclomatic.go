@@ -1,11 +1,50 @@
 package main
 
-import "github.com/bblfsh/tools"
+import (
+	"os"
+
+	"github.com/bblfsh/tools"
+)
 
 type CyclomaticComp struct {
 	Common
+	CyclomaticOptions
+	MaxCyclomatic int  `long:"max-cyclomatic" description:"maximum cyclomatic complexity of a function, exceeding it makes the command fail"`
+	Graph         bool `long:"graph" description:"also compute the complexity of the functions from their control flow graph, as E - N + 2P"`
+}
+
+// CyclomaticOptions selects the rules of the cyclomatic complexity, for the
+// tools computing it.
+type CyclomaticOptions struct {
+	Profile string `long:"profile" description:"rules deciding what adds complexity, following other tools" choice:"default" choice:"pmd" choice:"sonar" choice:"gocyclo" choice:"mccabe-strict" default:"default"`
+	Rules   string `long:"rules" description:"JSON file with the role combinations adding complexity, instead of a profile"`
 }
 
 func (c *CyclomaticComp) Execute(args []string) error {
-	return c.execute(args, tools.CyclomaticComplexity{})
+	profile, err := c.profile()
+	if err != nil {
+		return err
+	}
+	return c.execute(args, tools.CyclomaticComplexity{Profile: profile, Graph: c.Graph}, c.check)
+}
+
+func (o *CyclomaticOptions) profile() (*tools.CyclomaticProfile, error) {
+	if o.Rules == "" {
+		return tools.CyclomaticProfileByName(o.Profile)
+	}
+
+	f, err := os.Open(o.Rules)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return tools.ReadCyclomaticProfile(f)
+}
+
+func (c *CyclomaticComp) check(result tools.Result) []*violation {
+	var l limits
+	for i, f := range result.(*tools.CyclomaticResult).Functions {
+		l.check("cyclomatic complexity", c.MaxCyclomatic, f.Complexity, i, f.Name, f.Span)
+	}
+	return l
 }
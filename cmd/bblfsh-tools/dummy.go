@@ -7,5 +7,5 @@ type Dummy struct {
 }
 
 func (c *Dummy) Execute(args []string) error {
-	return c.execute(args, tools.Dummy{})
+	return c.execute(args, tools.Dummy{}, nil)
 }
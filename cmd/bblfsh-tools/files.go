@@ -0,0 +1,286 @@
+package main
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Sirupsen/logrus"
+)
+
+// gitDir is never walked, regardless of the .gitignore files.
+const gitDir = ".git"
+
+// inputFiles expands the positional arguments into the list of files to
+// analyze. Each argument can be a file, which is always analyzed, a
+// directory, which is walked recursively, or a glob pattern, where "**"
+// matches any number of directories.
+//
+// Only the files with a known language extension (or the UAST files, when
+// reading UASTs) matching the --include patterns are taken from the walked
+// directories and the glob matches, skipping the ones matching the
+// --exclude patterns or ignored by .gitignore files. The paths of the glob
+// matches are relative to the part of the pattern without meta characters.
+func (c *Common) inputFiles() ([]string, error) {
+	var files []string
+	seen := make(map[string]bool)
+	add := func(file string) {
+		if !seen[file] {
+			seen[file] = true
+			files = append(files, file)
+		}
+	}
+
+	for _, arg := range c.Args.Files {
+		if arg == stdinFile && c.UASTInput {
+			add(arg)
+			continue
+		}
+
+		if isGlob(arg) {
+			matched, err := c.globFiles(arg)
+			if err != nil {
+				return nil, err
+			}
+			for _, file := range matched {
+				add(file)
+			}
+			continue
+		}
+
+		info, err := os.Stat(arg)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			add(arg)
+			continue
+		}
+		walked, err := c.walk(arg)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range walked {
+			add(file)
+		}
+	}
+	return files, nil
+}
+
+// globFiles returns the files matching the glob pattern that should be
+// analyzed, walking the directories matching it. They are filtered as the
+// walked files, relative to the root of the pattern, and skipped if any of
+// their parent directories under the root is.
+func (c *Common) globFiles(pattern string) ([]string, error) {
+	matches, err := glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if len(matches) == 0 {
+		logrus.Warnf("no files matching %s", pattern)
+	}
+
+	root := filepath.FromSlash(globRoot(pattern))
+	ignore := newGitignore()
+	loaded := make(map[string]bool)
+
+	var files []string
+	for _, p := range matches {
+		skip, err := c.skippedDir(root, filepath.Dir(p), ignore, loaded)
+		if err != nil {
+			return nil, err
+		}
+		if skip {
+			continue
+		}
+
+		info, err := os.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			if info.Mode().IsRegular() && c.included(root, p) && !c.excluded(root, p, false, ignore) {
+				files = append(files, p)
+			}
+			continue
+		}
+		if info.Name() == gitDir || c.excluded(root, p, true, ignore) {
+			continue
+		}
+		walked, err := c.walk(p)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, walked...)
+	}
+	return files, nil
+}
+
+// skippedDir reports whether the directory dir, or any of its parents up to
+// root, is skipped as if root was walked, loading the .gitignore files of
+// the ones not loaded yet.
+func (c *Common) skippedDir(root, dir string, ignore *gitignore, loaded map[string]bool) (bool, error) {
+	root, dir = filepath.Clean(root), filepath.Clean(dir)
+	rel, err := filepath.Rel(root, dir)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return false, nil
+	}
+
+	dirs := []string{root}
+	if rel != "." {
+		for _, name := range strings.Split(rel, string(filepath.Separator)) {
+			dirs = append(dirs, filepath.Join(dirs[len(dirs)-1], name))
+		}
+	}
+
+	for _, d := range dirs {
+		if d != root && (filepath.Base(d) == gitDir || c.excluded(root, d, true, ignore)) {
+			return true, nil
+		}
+		if c.NoGitignore || loaded[d] {
+			continue
+		}
+		loaded[d] = true
+		if err := ignore.load(d); err != nil {
+			return false, err
+		}
+	}
+	return false, nil
+}
+
+// walk returns the files under root that should be analyzed, sorted by name.
+func (c *Common) walk(root string) ([]string, error) {
+	logrus.Debugf("walking directory %s", root)
+	ignore := newGitignore()
+
+	var files []string
+	err := filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			if p != root && (info.Name() == gitDir || c.excluded(root, p, true, ignore)) {
+				return filepath.SkipDir
+			}
+			if c.NoGitignore {
+				return nil
+			}
+			return ignore.load(p)
+		}
+
+		if info.Mode().IsRegular() && c.included(root, p) && !c.excluded(root, p, false, ignore) {
+			files = append(files, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
+
+func (c *Common) included(root, p string) bool {
+	if len(c.Include) == 0 {
+		if c.UASTInput {
+			return strings.HasSuffix(p, uastExtension)
+		}
+		return languageOf(p) != ""
+	}
+	return matchAny(c.Include, relativePath(root, p))
+}
+
+func (c *Common) excluded(root, p string, isDir bool, ignore *gitignore) bool {
+	if matchAny(c.Exclude, relativePath(root, p)) {
+		return true
+	}
+	return !c.NoGitignore && ignore.ignored(root, p, isDir)
+}
+
+// matchAny reports whether the slash separated path rel matches any of the
+// patterns. Patterns without a slash are matched against the base name.
+func matchAny(patterns []string, rel string) bool {
+	for _, pattern := range patterns {
+		pattern = filepath.ToSlash(pattern)
+		name := rel
+		if !strings.Contains(pattern, "/") {
+			name = path.Base(rel)
+		}
+		if matchPath(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func relativePath(root, p string) string {
+	rel, err := filepath.Rel(root, p)
+	if err != nil {
+		rel = p
+	}
+	return filepath.ToSlash(rel)
+}
+
+func isGlob(p string) bool {
+	return strings.ContainsAny(p, "*?[")
+}
+
+// glob returns the files and directories matching the pattern, sorted by
+// name. Unlike filepath.Glob it supports "**" segments.
+func glob(pattern string) ([]string, error) {
+	if !strings.Contains(pattern, "**") {
+		return filepath.Glob(pattern)
+	}
+
+	pattern = filepath.ToSlash(filepath.Clean(pattern))
+	// walk only from the longest prefix without meta characters
+	root := globRoot(pattern)
+
+	var matches []string
+	err := filepath.Walk(filepath.FromSlash(root), func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() && info.Name() == gitDir {
+			return filepath.SkipDir
+		}
+		if matchPath(pattern, filepath.ToSlash(p)) {
+			matches = append(matches, p)
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(matches)
+	return matches, nil
+}
+
+// globRoot returns the slash separated longest prefix of the pattern without
+// meta characters, the directory where its matches are searched from.
+func globRoot(pattern string) string {
+	pattern = filepath.ToSlash(filepath.Clean(pattern))
+	var base []string
+	for _, s := range strings.Split(pattern, "/") {
+		if isGlob(s) {
+			break
+		}
+		base = append(base, s)
+	}
+	root := strings.Join(base, "/")
+	if root == "" {
+		if strings.HasPrefix(pattern, "/") {
+			return "/"
+		}
+		return "."
+	}
+	return root
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fileTree creates the files under a temporary directory, with the content
+// given for the .gitignore files, and returns the directory.
+func fileTree(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "bblfsh-tools")
+	require.NoError(t, err)
+
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0755))
+		require.NoError(t, ioutil.WriteFile(p, []byte(content), 0644))
+	}
+	return dir
+}
+
+func TestInputFiles(t *testing.T) {
+	dir := fileTree(t, map[string]string{
+		".gitignore":          "build/\n*.gen.java\n!keep.gen.java\n",
+		"a.java":              "",
+		"b.py":                "",
+		"notes.txt":           "",
+		"x.gen.java":          "",
+		"keep.gen.java":       "",
+		"build/c.java":        "",
+		"sub/.gitignore":      "/local.java\n",
+		"sub/d.java":          "",
+		"sub/local.java":      "",
+		"sub/deep/local.java": "",
+		"vendor/e.java":       "",
+		".git/f.java":         "",
+	})
+	defer os.RemoveAll(dir)
+
+	path := func(name string) string {
+		return filepath.Join(dir, filepath.FromSlash(name))
+	}
+
+	cases := []struct {
+		name   string
+		common Common
+		args   []string
+		expect []string
+	}{{
+		name: "directory",
+		args: []string{dir},
+		expect: []string{
+			"a.java", "b.py", "keep.gen.java", "sub/d.java", "sub/deep/local.java", "vendor/e.java",
+		},
+	}, {
+		name:   "include and exclude",
+		common: Common{Include: []string{"*.java"}, Exclude: []string{"vendor", "sub/deep/**"}},
+		args:   []string{dir},
+		expect: []string{"a.java", "keep.gen.java", "sub/d.java"},
+	}, {
+		name:   "include with slash",
+		common: Common{Include: []string{"sub/**/*.java"}},
+		args:   []string{dir},
+		expect: []string{"sub/d.java", "sub/deep/local.java"},
+	}, {
+		name:   "no gitignore",
+		common: Common{NoGitignore: true},
+		args:   []string{path("sub")},
+		expect: []string{"sub/d.java", "sub/deep/local.java", "sub/local.java"},
+	}, {
+		name:   "explicit files are always analyzed",
+		args:   []string{path("notes.txt"), path("build/c.java"), path("notes.txt")},
+		expect: []string{"notes.txt", "build/c.java"},
+	}, {
+		name: "glob",
+		args: []string{filepath.Join(dir, "**", "*.java")},
+		expect: []string{
+			"a.java", "keep.gen.java", "sub/d.java", "sub/deep/local.java", "vendor/e.java",
+		},
+	}, {
+		name:   "glob with exclude",
+		common: Common{Exclude: []string{"vendor/**", "d.java"}},
+		args:   []string{filepath.Join(dir, "**", "*.java")},
+		expect: []string{"a.java", "keep.gen.java", "sub/deep/local.java"},
+	}, {
+		name:   "glob matching directories",
+		common: Common{Include: []string{"*.java"}},
+		args:   []string{filepath.Join(dir, "*")},
+		expect: []string{
+			"a.java", "keep.gen.java", "sub/d.java", "sub/deep/local.java", "vendor/e.java",
+		},
+	}, {
+		name:   "glob without gitignore",
+		common: Common{NoGitignore: true},
+		args:   []string{filepath.Join(dir, "**", "local.java")},
+		expect: []string{"sub/deep/local.java", "sub/local.java"},
+	}}
+
+	for _, c := range cases {
+		c.common.Args.Files = c.args
+		files, err := c.common.inputFiles()
+		require.NoError(t, err, c.name)
+
+		var got []string
+		for _, f := range files {
+			rel, err := filepath.Rel(dir, f)
+			require.NoError(t, err)
+			got = append(got, filepath.ToSlash(rel))
+		}
+		require.Equal(t, c.expect, got, c.name)
+	}
+}
+
+func TestInputFilesUAST(t *testing.T) {
+	require := require.New(t)
+
+	dir := fileTree(t, map[string]string{
+		"a.java.json": "",
+		"a.java":      "",
+		"b.json":      "",
+	})
+	defer os.RemoveAll(dir)
+
+	c := Common{UASTInput: true}
+	c.Args.Files = []string{dir, stdinFile}
+	files, err := c.inputFiles()
+	require.NoError(err)
+	require.Equal([]string{
+		filepath.Join(dir, "a.java.json"), filepath.Join(dir, "b.json"), stdinFile,
+	}, files)
+
+	c.Args.Files = []string{filepath.Join(dir, "missing.java")}
+	_, err = c.inputFiles()
+	require.Error(err)
+}
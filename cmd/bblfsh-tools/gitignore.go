@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+const gitignoreFile = ".gitignore"
+
+// gitignoreRule is a single pattern of a .gitignore file.
+type gitignoreRule struct {
+	pattern  string
+	negate   bool
+	dirOnly  bool
+	anchored bool
+}
+
+// match reports whether the rule matches the given slash separated path,
+// relative to the directory containing the .gitignore file.
+func (r *gitignoreRule) match(rel string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+	if r.anchored {
+		return matchPath(r.pattern, rel)
+	}
+	return matchPath(r.pattern, path.Base(rel))
+}
+
+func parseGitignoreLine(line string) *gitignoreRule {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return nil
+	}
+
+	r := &gitignoreRule{}
+	if strings.HasPrefix(line, "!") {
+		r.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		r.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if strings.Contains(line, "/") {
+		r.anchored = true
+		line = strings.TrimPrefix(line, "/")
+	}
+	if line == "" {
+		return nil
+	}
+	r.pattern = line
+	return r
+}
+
+// gitignore keeps the rules of every .gitignore file found while walking a
+// directory tree, indexed by the directory containing them.
+type gitignore struct {
+	rules map[string][]*gitignoreRule
+}
+
+func newGitignore() *gitignore {
+	return &gitignore{rules: make(map[string][]*gitignoreRule)}
+}
+
+// load reads the .gitignore file of dir, if any.
+func (g *gitignore) load(dir string) error {
+	file, err := os.Open(filepath.Join(dir, gitignoreFile))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var rules []*gitignoreRule
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if r := parseGitignoreLine(scanner.Text()); r != nil {
+			rules = append(rules, r)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if len(rules) > 0 {
+		g.rules[filepath.Clean(dir)] = rules
+	}
+	return nil
+}
+
+// ignored reports whether the file or directory at p is ignored by the rules
+// loaded for any of its parent directories up to root. As in git, the last
+// matching rule wins and rules in deeper directories take precedence.
+func (g *gitignore) ignored(root, p string, isDir bool) bool {
+	root = filepath.Clean(root)
+	var dirs []string
+	for dir := filepath.Dir(p); ; dir = filepath.Dir(dir) {
+		dirs = append(dirs, dir)
+		if dir == root || dir == filepath.Dir(dir) {
+			break
+		}
+	}
+
+	ignored := false
+	for i := len(dirs) - 1; i >= 0; i-- {
+		rel, err := filepath.Rel(dirs[i], p)
+		if err != nil {
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+		for _, r := range g.rules[dirs[i]] {
+			if r.match(rel, isDir) {
+				ignored = !r.negate
+			}
+		}
+	}
+	return ignored
+}
+
+// matchPath matches a slash separated path against a pattern with the syntax
+// of path.Match, extended with "**" segments matching any number of
+// directories.
+func matchPath(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, err := path.Match(pattern[0], name[0]); err != nil || !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchPath(t *testing.T) {
+	cases := []struct {
+		pattern, name string
+		expect        bool
+	}{
+		{"*.java", "a.java", true},
+		{"*.java", "a.py", false},
+		{"*.java", "src/a.java", false},
+		{"src/*.java", "src/a.java", true},
+		{"src/*.java", "src/main/a.java", false},
+		{"?.go", "a.go", true},
+		{"?.go", "ab.go", false},
+		{"[ab].go", "b.go", true},
+		{"[ab].go", "c.go", false},
+		// "**" matches any number of directories, even none
+		{"**/*.java", "a.java", true},
+		{"**/*.java", "src/main/a.java", true},
+		{"src/**", "src/main/a.java", true},
+		{"src/**", "lib/a.java", false},
+		{"src/**/a.java", "src/a.java", true},
+		{"src/**/a.java", "src/main/java/a.java", true},
+		{"src/**/a.java", "src/main/b.java", false},
+		{"**/test/**", "src/test/a.java", true},
+		{"**/test/**", "src/main/a.java", false},
+		// a malformed pattern matches nothing
+		{"[", "[", false},
+	}
+
+	for _, c := range cases {
+		require.Equal(t, c.expect, matchPath(c.pattern, c.name), "%s %s", c.pattern, c.name)
+	}
+}
+
+func TestParseGitignoreLine(t *testing.T) {
+	cases := []struct {
+		line   string
+		expect *gitignoreRule
+	}{
+		{"", nil},
+		{"   ", nil},
+		{"# comment", nil},
+		{"/", nil},
+		{"*.log", &gitignoreRule{pattern: "*.log"}},
+		{"*.log  \r", &gitignoreRule{pattern: "*.log"}},
+		{"!keep.log", &gitignoreRule{pattern: "keep.log", negate: true}},
+		{`\!important`, &gitignoreRule{pattern: "!important"}},
+		{`\#hash`, &gitignoreRule{pattern: "#hash"}},
+		{"build/", &gitignoreRule{pattern: "build", dirOnly: true}},
+		{"/root.txt", &gitignoreRule{pattern: "root.txt", anchored: true}},
+		{"docs/*.md", &gitignoreRule{pattern: "docs/*.md", anchored: true}},
+		{"!/out/", &gitignoreRule{pattern: "out", negate: true, dirOnly: true, anchored: true}},
+	}
+
+	for _, c := range cases {
+		require.Equal(t, c.expect, parseGitignoreLine(c.line), c.line)
+	}
+}
+
+func TestGitignoreRuleMatch(t *testing.T) {
+	cases := []struct {
+		line  string
+		rel   string
+		isDir bool
+		match bool
+	}{
+		// unanchored patterns match the base name at any depth
+		{"*.log", "a.log", false, true},
+		{"*.log", "logs/a.log", false, true},
+		{"*.log", "a.txt", false, false},
+		// anchored patterns match the path from the .gitignore directory
+		{"/a.log", "a.log", false, true},
+		{"/a.log", "logs/a.log", false, false},
+		{"logs/*.log", "logs/a.log", false, true},
+		{"logs/*.log", "src/logs/a.log", false, false},
+		{"**/logs", "src/logs", true, true},
+		// the directory rules only match directories
+		{"build/", "build", true, true},
+		{"build/", "build", false, false},
+		{"build/", "src/build", true, true},
+	}
+
+	for _, c := range cases {
+		r := parseGitignoreLine(c.line)
+		require.Equal(t, c.match, r.match(c.rel, c.isDir), "%s %s", c.line, c.rel)
+	}
+}
+
+func TestGitignoreIgnored(t *testing.T) {
+	require := require.New(t)
+
+	root := filepath.FromSlash("/repo")
+	sub := filepath.Join(root, "sub")
+	g := newGitignore()
+	g.rules[root] = []*gitignoreRule{
+		parseGitignoreLine("*.log"),
+		parseGitignoreLine("!keep.log"),
+		parseGitignoreLine("tmp/"),
+	}
+	g.rules[sub] = []*gitignoreRule{
+		parseGitignoreLine("!*.log"),
+		parseGitignoreLine("/local.txt"),
+	}
+
+	cases := []struct {
+		path    string
+		isDir   bool
+		ignored bool
+	}{
+		{"a.log", false, true},
+		// the last matching rule wins
+		{"keep.log", false, false},
+		{"tmp", true, true},
+		{"tmp", false, false},
+		{"a.txt", false, false},
+		// the rules of deeper directories take precedence
+		{"sub/a.log", false, false},
+		{"sub/local.txt", false, true},
+		{"sub/deep/local.txt", false, false},
+		{"local.txt", false, false},
+	}
+
+	for _, c := range cases {
+		p := filepath.Join(root, filepath.FromSlash(c.path))
+		require.Equal(c.ignored, g.ignored(root, p, c.isDir), c.path)
+	}
+}
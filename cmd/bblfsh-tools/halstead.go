@@ -0,0 +1,11 @@
+package main
+
+import "github.com/bblfsh/tools"
+
+type Halstead struct {
+	Common
+}
+
+func (c *Halstead) Execute(args []string) error {
+	return c.execute(args, tools.Halstead{}, nil)
+}
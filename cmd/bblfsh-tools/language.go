@@ -0,0 +1,42 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// uastExtension is the extension of the files read with --uast-input.
+const uastExtension = ".json"
+
+// languages maps the file extensions to the languages supported by the
+// Babelfish drivers.
+var languages = map[string]string{
+	".bash": "bash",
+	".c":    "cpp",
+	".cc":   "cpp",
+	".cpp":  "cpp",
+	".cs":   "csharp",
+	".cxx":  "cpp",
+	".go":   "go",
+	".h":    "cpp",
+	".hpp":  "cpp",
+	".java": "java",
+	".js":   "javascript",
+	".jsx":  "javascript",
+	".php":  "php",
+	".py":   "python",
+	".rb":   "ruby",
+	".sh":   "bash",
+	".ts":   "typescript",
+	".tsx":  "typescript",
+}
+
+// languageOf guesses the language of a file from its extension. UAST files
+// are expected to be named after the source file they were parsed from,
+// e.g. Foo.java.json. It returns an empty string for unknown extensions.
+func languageOf(file string) string {
+	if strings.HasSuffix(file, uastExtension) {
+		file = strings.TrimSuffix(file, uastExtension)
+	}
+	return languages[strings.ToLower(filepath.Ext(file))]
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/bblfsh/tools"
+)
+
+// violation is a metric of a function exceeding its limit.
+type violation struct {
+	Function string `json:"function"`
+	Metric   string `json:"metric"`
+	Value    int    `json:"value"`
+	Limit    int    `json:"limit"`
+	// Capped is set when the value was too big to be represented.
+	Capped bool `json:"capped,omitempty"`
+	tools.Span
+	// index is the position of the function in the result, which tells
+	// apart the functions with the same name.
+	index int
+}
+
+func (v *violation) String() string {
+	value := strconv.Itoa(v.Value)
+	if v.Capped {
+		value += " (capped)"
+	}
+	return fmt.Sprintf("Limit exceeded: %s of %s is %s (max %d) at %s",
+		v.Metric, v.Function, value, v.Limit, v.Span)
+}
+
+// checkFunc returns the violations of the metric limits found in a result.
+type checkFunc func(tools.Result) []*violation
+
+// limits accumulates the violations of the metric limits of a result.
+type limits []*violation
+
+// check adds a violation if max is set and value exceeds it, for the
+// function at index in the result.
+func (l *limits) check(metric string, max, value, index int, function string, span tools.Span) {
+	l.checkCapped(metric, max, value, false, index, function, span)
+}
+
+// checkCapped is like check for values that may be capped, which always
+// exceed max.
+func (l *limits) checkCapped(metric string, max, value int, capped bool, index int, function string, span tools.Span) {
+	if max <= 0 || (value <= max && !capped) {
+		return
+	}
+	*l = append(*l, &violation{
+		Function: function,
+		Metric:   metric,
+		Value:    value,
+		Limit:    max,
+		Capped:   capped,
+		Span:     span,
+		index:    index,
+	})
+}
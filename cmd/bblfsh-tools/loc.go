@@ -0,0 +1,11 @@
+package main
+
+import "github.com/bblfsh/tools"
+
+type LOC struct {
+	Common
+}
+
+func (c *LOC) Execute(args []string) error {
+	return c.execute(args, tools.LOC{}, nil)
+}
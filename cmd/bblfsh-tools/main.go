@@ -7,6 +7,14 @@ import (
 	"github.com/jessevdk/go-flags"
 )
 
+// Exit codes of the command.
+const (
+	exitError = 1
+	// exitLimits is used when the analysis succeeds but some metric
+	// exceeds the limit given by the user.
+	exitLimits = 2
+)
+
 func init() {
 	logrus.SetLevel(logrus.DebugLevel)
 }
@@ -17,6 +25,15 @@ func main() {
 	parser.AddCommand("tokenizer", "", "Run tokenizer tool", &Tokenizer{})
 	parser.AddCommand("cyclomatic", "", "Run cyclomatic complexity tool", &CyclomaticComp{})
 	parser.AddCommand("npath", "", "Run npath complexity calculation", &NPath{})
+	parser.AddCommand("cognitive", "", "Run cognitive complexity tool", &Cognitive{})
+	parser.AddCommand("halstead", "", "Run Halstead metrics tool", &Halstead{})
+	parser.AddCommand("maintainability", "", "Run maintainability index tool", &Maintainability{})
+	parser.AddCommand("loc", "", "Run lines of code tool", &LOC{})
+	parser.AddCommand("nesting", "", "Run nesting depth tool", &Nesting{})
+	parser.AddCommand("signature", "", "Run function signature metrics tool", &Signature{})
+	parser.AddCommand("abc", "", "Run ABC metric tool", &ABC{})
+	parser.AddCommand("oo-metrics", "", "Run object oriented metrics tool", &OOMetrics{})
+	parser.AddCommand("cfg", "", "Run control flow graph tool", &ControlFlow{})
 
 	if _, err := parser.Parse(); err != nil {
 		if flagsErr, ok := err.(*flags.Error); ok {
@@ -24,12 +41,15 @@ func main() {
 				os.Exit(0)
 			} else {
 				parser.WriteHelp(os.Stderr)
-				os.Exit(1)
+				os.Exit(exitError)
 			}
 		}
 
 		logrus.Errorf("exiting with error: %s", err)
-		os.Exit(1)
+		if ErrLimits.Is(err) {
+			os.Exit(exitLimits)
+		}
+		os.Exit(exitError)
 	}
 	logrus.Debug("exiting without error")
 }
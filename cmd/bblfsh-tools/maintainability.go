@@ -0,0 +1,17 @@
+package main
+
+import "github.com/bblfsh/tools"
+
+type Maintainability struct {
+	Common
+	CyclomaticOptions
+	Comments bool `long:"comments" description:"add the term of the ratio of comment lines to the index"`
+}
+
+func (c *Maintainability) Execute(args []string) error {
+	profile, err := c.profile()
+	if err != nil {
+		return err
+	}
+	return c.execute(args, tools.Maintainability{Comments: c.Comments, Profile: profile}, nil)
+}
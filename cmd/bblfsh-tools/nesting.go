@@ -0,0 +1,20 @@
+package main
+
+import "github.com/bblfsh/tools"
+
+type Nesting struct {
+	Common
+	MaxNesting int `long:"max-nesting" description:"maximum nesting depth of the control structures of a function, exceeding it makes the command fail"`
+}
+
+func (c *Nesting) Execute(args []string) error {
+	return c.execute(args, tools.Nesting{}, c.check)
+}
+
+func (c *Nesting) check(result tools.Result) []*violation {
+	var l limits
+	for i, f := range result.(*tools.NestingResult).Functions {
+		l.check("nesting depth", c.MaxNesting, f.MaxDepth, i, f.Name, f.Deepest)
+	}
+	return l
+}
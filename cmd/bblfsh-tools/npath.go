@@ -4,8 +4,18 @@ import "github.com/bblfsh/tools"
 
 type NPath struct {
 	Common
+	MaxNPath      int  `long:"max-npath" description:"maximum NPath complexity of a function, exceeding it makes the command fail"`
+	ExcludeNested bool `long:"exclude-nested" description:"do not count the nested functions and lambdas in the complexity of the function declaring them"`
 }
 
 func (c *NPath) Execute(args []string) error {
-	return c.execute(args, tools.NPath{})
+	return c.execute(args, tools.NPath{ExcludeNested: c.ExcludeNested}, c.check)
+}
+
+func (c *NPath) check(result tools.Result) []*violation {
+	var l limits
+	for i, f := range result.(*tools.NPathResult).Functions {
+		l.checkCapped("npath complexity", c.MaxNPath, f.Complexity, f.Capped, i, f.Name, f.Span)
+	}
+	return l
 }
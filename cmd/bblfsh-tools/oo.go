@@ -0,0 +1,16 @@
+package main
+
+import "github.com/bblfsh/tools"
+
+type OOMetrics struct {
+	Common
+	CyclomaticOptions
+}
+
+func (c *OOMetrics) Execute(args []string) error {
+	profile, err := c.profile()
+	if err != nil {
+		return err
+	}
+	return c.execute(args, tools.OOMetrics{Profile: profile}, nil)
+}
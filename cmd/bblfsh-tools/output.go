@@ -0,0 +1,248 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/bblfsh/tools"
+
+	"gopkg.in/src-d/go-errors.v1"
+)
+
+var ErrUnknownFormat = errors.NewKind("Unknown output format %s")
+
+// Output formats.
+const (
+	formatText   = "text"
+	formatJSON   = "json"
+	formatNDJSON = "ndjson"
+	formatCSV    = "csv"
+)
+
+// fileResult is the outcome of analyzing a file.
+type fileResult struct {
+	File       string       `json:"file"`
+	Language   string       `json:"language"`
+	Result     tools.Result `json:"result,omitempty"`
+	Error      string       `json:"error,omitempty"`
+	Violations []*violation `json:"violations,omitempty"`
+}
+
+// output writes the results of a run in one of the output formats.
+type output interface {
+	// write is called with the result of every file, in order.
+	write(*fileResult) error
+	// close is called once all the files are analyzed.
+	close(*summary) error
+}
+
+// newOutput returns the output writing to w in the format. The text output
+// writes the file names and the summary to headers instead, if not nil, and
+// the CSV output the summary, to the standard error if nil.
+func newOutput(w, headers io.Writer, format string, multiple bool) (output, error) {
+	switch format {
+	case formatText, "":
+		if headers == nil {
+			headers = w
+		}
+		return &textOutput{w: w, headers: headers, multiple: multiple}, nil
+	case formatJSON:
+		return &jsonOutput{w: w}, nil
+	case formatNDJSON:
+		return &ndjsonOutput{enc: json.NewEncoder(w)}, nil
+	case formatCSV:
+		if headers == nil {
+			headers = os.Stderr
+		}
+		return &csvOutput{w: csv.NewWriter(w), summary: headers}, nil
+	default:
+		return nil, ErrUnknownFormat.New(format)
+	}
+}
+
+// textOutput prints the human readable results. When several files are
+// analyzed, each result is preceded by the file name and a summary is
+// printed at the end, both to headers.
+type textOutput struct {
+	w        io.Writer
+	headers  io.Writer
+	multiple bool
+}
+
+func (o *textOutput) write(r *fileResult) error {
+	if o.multiple {
+		if _, err := fmt.Fprintf(o.headers, "%s:\n", r.File); err != nil {
+			return err
+		}
+	}
+	if r.Result == nil {
+		return nil
+	}
+	if err := renderText(o.w, r.Result); err != nil {
+		return err
+	}
+	for _, v := range r.Violations {
+		if _, err := fmt.Fprintln(o.w, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (o *textOutput) close(s *summary) error {
+	if !o.multiple {
+		return nil
+	}
+	_, err := fmt.Fprintln(o.headers, s)
+	return err
+}
+
+// jsonOutput writes a single JSON document with the results of all the
+// files and the summary.
+type jsonOutput struct {
+	w     io.Writer
+	files []*fileResult
+}
+
+func (o *jsonOutput) write(r *fileResult) error {
+	o.files = append(o.files, r)
+	return nil
+}
+
+func (o *jsonOutput) close(s *summary) error {
+	if o.files == nil {
+		o.files = []*fileResult{}
+	}
+	enc := json.NewEncoder(o.w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(struct {
+		Files   []*fileResult `json:"files"`
+		Summary *summary      `json:"summary"`
+	}{o.files, s})
+}
+
+// ndjsonOutput writes the result of every file as a JSON document in its
+// own line, as soon as it is available, and the summary in the last line.
+type ndjsonOutput struct {
+	enc *json.Encoder
+}
+
+func (o *ndjsonOutput) write(r *fileResult) error {
+	return o.enc.Encode(r)
+}
+
+func (o *ndjsonOutput) close(s *summary) error {
+	return o.enc.Encode(struct {
+		Summary *summary `json:"summary"`
+	}{s})
+}
+
+// csvOutput writes a row for every record of the results, prefixed by the
+// file and language columns and followed by the exceeded limits of the
+// function of the record and the error of the file. The header is written
+// along the first result, so the rows of the files failing before it are
+// kept until then. Failed files have a row with just their error, and the
+// summary is written to its own writer, to keep the same columns in all the
+// rows.
+type csvOutput struct {
+	w       *csv.Writer
+	summary io.Writer
+	// columns is the number of columns of the header, zero until it's
+	// written
+	columns int
+	failed  []*fileResult
+}
+
+func (o *csvOutput) write(r *fileResult) error {
+	if r.Result == nil {
+		o.failed = append(o.failed, r)
+		if o.columns == 0 {
+			return nil
+		}
+		return o.writeFailed()
+	}
+
+	header, rows, err := renderRecords(r.Result)
+	if err != nil {
+		return err
+	}
+
+	if o.columns == 0 {
+		if err := o.writeHeader(header); err != nil {
+			return err
+		}
+		if err := o.writeFailed(); err != nil {
+			return err
+		}
+	}
+	for i, row := range rows {
+		// the rows of the functions come in the same order as in the
+		// result, the one of the index of the violations
+		var violations []*violation
+		for _, v := range r.Violations {
+			if v.index == i {
+				violations = append(violations, v)
+			}
+		}
+		row = append(append([]string{r.File, r.Language}, row...), violationsRecord(violations), "")
+		if err := o.w.Write(row); err != nil {
+			return err
+		}
+	}
+	o.w.Flush()
+	return o.w.Error()
+}
+
+func (o *csvOutput) writeHeader(header []string) error {
+	header = append(append([]string{"file", "language"}, header...), "violations", "error")
+	o.columns = len(header)
+	return o.w.Write(header)
+}
+
+// writeFailed writes the rows of the failed files, once the header is
+// written.
+func (o *csvOutput) writeFailed() error {
+	for _, r := range o.failed {
+		row := make([]string, o.columns)
+		row[0], row[1], row[o.columns-1] = r.File, r.Language, r.Error
+		if err := o.w.Write(row); err != nil {
+			return err
+		}
+	}
+	o.failed = nil
+	o.w.Flush()
+	return o.w.Error()
+}
+
+func (o *csvOutput) close(s *summary) error {
+	if o.columns == 0 && len(o.failed) > 0 {
+		// all the files failed, so there are only the common columns
+		if err := o.writeHeader(nil); err != nil {
+			return err
+		}
+		if err := o.writeFailed(); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintln(o.summary, s)
+	return err
+}
+
+// violationsRecord joins the violations in a single column, as "metric:
+// value > limit".
+func violationsRecord(violations []*violation) string {
+	messages := make([]string, len(violations))
+	for i, v := range violations {
+		value := strconv.Itoa(v.Value)
+		if v.Capped {
+			value += " (capped)"
+		}
+		messages[i] = fmt.Sprintf("%s: %s > %d", v.Metric, value, v.Limit)
+	}
+	return strings.Join(messages, "; ")
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/bblfsh/tools"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCSVOutput(t *testing.T) {
+	require := require.New(t)
+
+	var buf, summaryBuf bytes.Buffer
+	out, err := newOutput(&buf, &summaryBuf, formatCSV, true)
+	require.NoError(err)
+
+	span := tools.Span{StartLine: 1, EndLine: 2}
+	// the file failing before the header is written after it
+	require.NoError(out.write(&fileResult{File: "a.java", Language: "java", Error: "failed"}))
+	require.NoError(out.write(&fileResult{
+		File:     "b.java",
+		Language: "java",
+		Result: &tools.CyclomaticResult{Complexity: 7, Functions: []*tools.CyclomaticData{
+			{Name: "f", Complexity: 2, Span: span},
+			{Name: "f", Complexity: 4, Span: span},
+			{Name: "g", Complexity: 1, Span: span},
+		}},
+		Violations: []*violation{{Function: "f", Metric: "cyclomatic complexity", Value: 4, Limit: 2, index: 1}},
+	}))
+	require.NoError(out.write(&fileResult{File: "c.java", Language: "java", Error: "failed again"}))
+	require.NoError(out.close(&summary{Files: 3, Failed: 2, Violations: 1}))
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(err)
+	require.Len(records, 7)
+	header := records[0]
+	violations, errors := len(header)-2, len(header)-1
+	require.Equal([]string{"violations", "error"}, header[violations:])
+
+	require.Equal([]string{"a.java", "java"}, records[1][:2])
+	require.Equal("failed", records[1][errors])
+	// only the function with the same name exceeding the limit
+	require.Equal("", records[2][violations])
+	require.Equal("cyclomatic complexity: 4 > 2", records[3][violations])
+	require.Equal("", records[4][violations])
+	// the total of the file has no function
+	require.Equal([]string{"b.java", "java", "", "7"}, records[5][:4])
+	require.Equal([]string{"c.java", "java"}, records[6][:2])
+	require.Equal("failed again", records[6][errors])
+
+	require.Equal("Files analyzed: 3, failed: 2, limits exceeded: 1\n", summaryBuf.String())
+}
+
+func TestCSVOutputFailed(t *testing.T) {
+	require := require.New(t)
+
+	var buf, summaryBuf bytes.Buffer
+	out, err := newOutput(&buf, &summaryBuf, formatCSV, true)
+	require.NoError(err)
+	require.NoError(out.write(&fileResult{File: "a.java", Language: "java", Error: "failed"}))
+	require.NoError(out.close(&summary{Files: 1, Failed: 1}))
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(err)
+	require.Equal([][]string{
+		{"file", "language", "violations", "error"},
+		{"a.java", "java", "", "failed"},
+	}, records)
+}
+
+func TestNDJSONOutput(t *testing.T) {
+	require := require.New(t)
+
+	var buf bytes.Buffer
+	out, err := newOutput(&buf, nil, formatNDJSON, true)
+	require.NoError(err)
+	require.NoError(out.write(&fileResult{File: "a.java", Language: "java", Error: "failed"}))
+	require.NoError(out.close(&summary{Files: 1, Failed: 1}))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(lines, 2)
+	var last struct {
+		Summary *summary `json:"summary"`
+	}
+	require.NoError(json.Unmarshal([]byte(lines[1]), &last))
+	require.Equal(&summary{Files: 1, Failed: 1}, last.Summary)
+}
+
+func TestTextOutputHeaders(t *testing.T) {
+	require := require.New(t)
+
+	result := &fileResult{File: "a.java", Result: &tools.DummyResult{Message: "ok"}}
+
+	var buf bytes.Buffer
+	out, err := newOutput(&buf, nil, formatText, true)
+	require.NoError(err)
+	require.NoError(out.write(result))
+	require.NoError(out.close(&summary{Files: 1}))
+	require.Equal("a.java:\nok\nFiles analyzed: 1, failed: 0, limits exceeded: 0\n", buf.String())
+
+	var results, headers bytes.Buffer
+	out, err = newOutput(&results, &headers, formatText, true)
+	require.NoError(err)
+	require.NoError(out.write(result))
+	require.NoError(out.close(&summary{Files: 1}))
+	require.Equal("ok\n", results.String())
+	require.Equal("a.java:\nFiles analyzed: 1, failed: 0, limits exceeded: 0\n", headers.String())
+}
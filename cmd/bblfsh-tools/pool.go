@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"runtime"
+	"sync"
+
+	"gopkg.in/bblfsh/sdk.v1/uast"
+)
+
+// pendingPerWorker bounds how many files can be parsed ahead of the one
+// being consumed, so a slow file doesn't make the rest pile up in memory.
+const pendingPerWorker = 2
+
+// parsed is the outcome of loading the UAST of a file.
+type parsed struct {
+	index int
+	file  string
+	uast  *uast.Node
+	// source is the content of the file, empty if unknown.
+	source string
+	err    error
+}
+
+// loadFunc returns the UAST of a file and, when known, its source code.
+type loadFunc func(ctx context.Context, file string) (*uast.Node, string, error)
+
+func (c *Common) workers() int {
+	if c.Workers > 0 {
+		return c.Workers
+	}
+	return runtime.NumCPU()
+}
+
+// loadFiles loads the UAST of the files with a pool of workers. The results
+// are sent to the returned channel in the same order as the files, no
+// matter which one finishes first. The channel is closed once all the files
+// are loaded or the context is cancelled.
+func (c *Common) loadFiles(ctx context.Context, files []string, load loadFunc) <-chan *parsed {
+	workers := c.workers()
+	jobs := make(chan int)
+	done := make(chan *parsed)
+	out := make(chan *parsed)
+	slots := make(chan struct{}, workers*pendingPerWorker)
+
+	go func() {
+		defer close(jobs)
+		for i := range files {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+
+			select {
+			case jobs <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				node, source, err := load(ctx, files[i])
+				select {
+				case done <- &parsed{index: i, file: files[i], uast: node, source: source, err: err}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	go func() {
+		defer close(out)
+		pending := make(map[int]*parsed)
+		next := 0
+		for p := range done {
+			pending[p.index] = p
+			for {
+				p, ok := pending[next]
+				if !ok {
+					break
+				}
+				delete(pending, next)
+
+				select {
+				case out <- p:
+				case <-ctx.Done():
+					return
+				}
+				<-slots
+				next++
+			}
+		}
+	}()
+
+	return out
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/bblfsh/sdk.v1/uast"
+)
+
+func testFiles(n int) []string {
+	files := make([]string, n)
+	for i := range files {
+		files[i] = fmt.Sprintf("file%d", i)
+	}
+	return files
+}
+
+// requireNoLeaks waits for the number of goroutines to go back to the given
+// one, failing if it doesn't.
+func requireNoLeaks(t *testing.T, goroutines int) {
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > goroutines && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	require.True(t, runtime.NumGoroutine() <= goroutines,
+		"%d goroutines left, %d expected", runtime.NumGoroutine(), goroutines)
+}
+
+func TestLoadFilesOrder(t *testing.T) {
+	require := require.New(t)
+	goroutines := runtime.NumGoroutine()
+
+	files := testFiles(20)
+	failed := files[10]
+	// the last files finish first
+	load := func(ctx context.Context, file string) (*uast.Node, string, error) {
+		var i int
+		fmt.Sscanf(file, "file%d", &i)
+		time.Sleep(time.Duration(len(files)-i) * time.Millisecond)
+		if file == failed {
+			return nil, "", fmt.Errorf("cannot parse %s", file)
+		}
+		return &uast.Node{InternalType: file}, "source of " + file, nil
+	}
+
+	c := &Common{Workers: 4}
+	var got []string
+	for p := range c.loadFiles(context.Background(), files, load) {
+		got = append(got, p.file)
+		if p.file == failed {
+			require.Error(p.err)
+			require.Nil(p.uast)
+			continue
+		}
+		require.NoError(p.err)
+		require.Equal(p.file, p.uast.InternalType)
+		require.Equal("source of "+p.file, p.source)
+	}
+	require.Equal(files, got)
+	requireNoLeaks(t, goroutines)
+}
+
+func TestLoadFilesBackpressure(t *testing.T) {
+	require := require.New(t)
+	goroutines := runtime.NumGoroutine()
+
+	var started int32
+	load := func(ctx context.Context, file string) (*uast.Node, string, error) {
+		atomic.AddInt32(&started, 1)
+		return &uast.Node{}, "", nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &Common{Workers: 2}
+	out := c.loadFiles(ctx, testFiles(50), load)
+
+	// nothing is consumed, so at most the files fitting in the slots are
+	// loaded
+	ahead := int32(c.Workers * pendingPerWorker)
+	time.Sleep(50 * time.Millisecond)
+	loaded := atomic.LoadInt32(&started)
+	require.True(loaded > 0 && loaded <= ahead, "%d files loaded ahead", loaded)
+
+	// every file consumed frees a slot
+	for i := 0; i < 3; i++ {
+		<-out
+	}
+	time.Sleep(50 * time.Millisecond)
+	after := atomic.LoadInt32(&started)
+	require.True(after > loaded && after <= ahead+3, "%d files loaded ahead", after)
+
+	cancel()
+	for range out {
+	}
+	require.True(atomic.LoadInt32(&started) < 50)
+	requireNoLeaks(t, goroutines)
+}
+
+func TestLoadFilesCancel(t *testing.T) {
+	require := require.New(t)
+	goroutines := runtime.NumGoroutine()
+
+	files := testFiles(100)
+	var started int32
+	load := func(ctx context.Context, file string) (*uast.Node, string, error) {
+		atomic.AddInt32(&started, 1)
+		if file == files[5] {
+			return nil, "", fmt.Errorf("cannot parse %s", file)
+		}
+		// the first files are the slowest
+		var i int
+		fmt.Sscanf(file, "file%d", &i)
+		if i < 5 {
+			time.Sleep(time.Duration(10-i) * time.Millisecond)
+		}
+		return &uast.Node{}, "", nil
+	}
+
+	// the context is cancelled on the first error, which stops loading the
+	// rest of the files and closes the channel
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := &Common{Workers: 4}
+	var got []string
+	for p := range c.loadFiles(ctx, files, load) {
+		got = append(got, p.file)
+		if p.err != nil {
+			cancel()
+		}
+	}
+
+	// the files after the failed one may be received or not, but in order
+	require.True(len(got) >= 6)
+	require.Equal(files[:len(got)], got)
+	require.True(atomic.LoadInt32(&started) < int32(len(files)))
+	requireNoLeaks(t, goroutines)
+}
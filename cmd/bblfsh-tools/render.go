@@ -0,0 +1,268 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/bblfsh/tools"
+
+	"gopkg.in/src-d/go-errors.v1"
+)
+
+var ErrUnknownResult = errors.NewKind("Unknown result type %T")
+
+// renderText writes the human readable representation of a tool result.
+func renderText(w io.Writer, result tools.Result) error {
+	var err error
+	switch r := result.(type) {
+	case *tools.DummyResult:
+		_, err = fmt.Fprintln(w, r.Message)
+	case *tools.TokenizerResult:
+		for _, token := range r.Tokens {
+			if _, err = fmt.Fprintln(w, token); err != nil {
+				break
+			}
+		}
+	case *tools.CyclomaticResult:
+		for _, f := range r.Functions {
+			if _, err = fmt.Fprint(w, f); err != nil {
+				return err
+			}
+		}
+		_, err = fmt.Fprintln(w, "Cyclomatic Complexity = ", r.Complexity)
+	case *tools.NPathResult:
+		for _, f := range r.Functions {
+			if _, err = fmt.Fprint(w, f); err != nil {
+				return err
+			}
+			if err = renderWarnings(w, f.Warnings); err != nil {
+				return err
+			}
+		}
+	case *tools.CognitiveResult:
+		for _, f := range r.Functions {
+			if _, err = fmt.Fprint(w, f); err != nil {
+				return err
+			}
+		}
+	case *tools.HalsteadResult:
+		for _, f := range r.Functions {
+			if _, err = fmt.Fprint(w, f); err != nil {
+				return err
+			}
+		}
+		_, err = fmt.Fprintln(w, "Halstead metrics =", r.File)
+	case *tools.MaintainabilityResult:
+		for _, f := range r.Functions {
+			if _, err = fmt.Fprint(w, f); err != nil {
+				return err
+			}
+		}
+	case *tools.LOCResult:
+		for _, f := range r.Functions {
+			if _, err = fmt.Fprint(w, f); err != nil {
+				return err
+			}
+		}
+		_, err = fmt.Fprintln(w, "Lines =", r.File)
+	case *tools.NestingResult:
+		for _, f := range r.Functions {
+			if _, err = fmt.Fprint(w, f); err != nil {
+				return err
+			}
+		}
+	case *tools.SignatureResult:
+		for _, f := range r.Functions {
+			if _, err = fmt.Fprint(w, f); err != nil {
+				return err
+			}
+		}
+	case *tools.ABCResult:
+		for _, f := range r.Functions {
+			if _, err = fmt.Fprint(w, f); err != nil {
+				return err
+			}
+		}
+	case *tools.OOResult:
+		for _, t := range r.Types {
+			if _, err = fmt.Fprint(w, t); err != nil {
+				return err
+			}
+		}
+	case *tools.ControlFlowResult:
+		for _, f := range r.Functions {
+			if err = f.Graph.WriteDOT(w, f.Name); err != nil {
+				return err
+			}
+		}
+	default:
+		err = ErrUnknownResult.New(result)
+	}
+	return err
+}
+
+// renderRecords returns the header and the rows of the tabular
+// representation of a tool result, used by the CSV output.
+func renderRecords(result tools.Result) ([]string, [][]string, error) {
+	var header []string
+	var rows [][]string
+	switch r := result.(type) {
+	case *tools.DummyResult:
+		header = []string{"message"}
+		rows = append(rows, []string{r.Message})
+	case *tools.TokenizerResult:
+		header = []string{"token"}
+		for _, token := range r.Tokens {
+			rows = append(rows, []string{token})
+		}
+	case *tools.CyclomaticResult:
+		header = append([]string{"function", "complexity", "graph_complexity"}, spanHeader...)
+		for _, f := range r.Functions {
+			var graph string
+			if f.GraphComplexity > 0 {
+				graph = strconv.Itoa(f.GraphComplexity)
+			}
+			rows = append(rows, append([]string{f.Name, strconv.Itoa(f.Complexity), graph}, spanRecord(f.Span)...))
+		}
+		// the total of the file, without a function
+		total := append([]string{"", strconv.Itoa(r.Complexity), ""}, make([]string, len(spanHeader))...)
+		rows = append(rows, total)
+	case *tools.NPathResult:
+		header = append([]string{"function", "complexity", "capped"}, spanHeader...)
+		header = append(header, "warnings")
+		for _, f := range r.Functions {
+			row := []string{f.Name, strconv.Itoa(f.Complexity), strconv.FormatBool(f.Capped)}
+			row = append(row, spanRecord(f.Span)...)
+			rows = append(rows, append(row, warningsRecord(f.Warnings)))
+		}
+	case *tools.CognitiveResult:
+		header = append([]string{"function", "complexity"}, spanHeader...)
+		for _, f := range r.Functions {
+			rows = append(rows, append([]string{f.Name, strconv.Itoa(f.Complexity)}, spanRecord(f.Span)...))
+		}
+	case *tools.HalsteadResult:
+		header = append([]string{"function"}, halsteadHeader...)
+		header = append(header, spanHeader...)
+		for _, f := range r.Functions {
+			row := append([]string{f.Name}, halsteadRecord(f.HalsteadMetrics)...)
+			rows = append(rows, append(row, spanRecord(f.Span)...))
+		}
+	case *tools.MaintainabilityResult:
+		header = append([]string{"function", "index", "normalized", "volume", "cyclomatic", "lines", "comment_lines"}, spanHeader...)
+		for _, f := range r.Functions {
+			row := []string{
+				f.Name, formatFloat(f.Index), formatFloat(f.Normalized), formatFloat(f.Volume),
+				strconv.Itoa(f.Cyclomatic), strconv.Itoa(f.Lines), strconv.Itoa(f.CommentLines),
+			}
+			rows = append(rows, append(row, spanRecord(f.Span)...))
+		}
+	case *tools.LOCResult:
+		header = append([]string{"function", "physical", "source", "logical", "comment", "blank"}, spanHeader...)
+		for _, f := range r.Functions {
+			row := []string{
+				f.Name, strconv.Itoa(f.Physical), strconv.Itoa(f.Source), strconv.Itoa(f.Logical),
+				strconv.Itoa(f.Comment), strconv.Itoa(f.Blank),
+			}
+			rows = append(rows, append(row, spanRecord(f.Span)...))
+		}
+	case *tools.NestingResult:
+		header = append([]string{"function", "max_depth", "average_depth", "deepest_line", "deepest_col"}, spanHeader...)
+		for _, f := range r.Functions {
+			row := []string{
+				f.Name, strconv.Itoa(f.MaxDepth), formatFloat(f.AverageDepth),
+				formatUint(f.Deepest.StartLine), formatUint(f.Deepest.StartCol),
+			}
+			rows = append(rows, append(row, spanRecord(f.Span)...))
+		}
+	case *tools.SignatureResult:
+		header = append([]string{"function", "arguments", "returns", "statements", "lines"}, spanHeader...)
+		for _, f := range r.Functions {
+			row := []string{
+				f.Name, strconv.Itoa(f.Arguments), strconv.Itoa(f.Returns),
+				strconv.Itoa(f.Statements), strconv.Itoa(f.Lines),
+			}
+			rows = append(rows, append(row, spanRecord(f.Span)...))
+		}
+	case *tools.ABCResult:
+		header = append([]string{"function", "assignments", "branches", "conditions", "magnitude"}, spanHeader...)
+		for _, f := range r.Functions {
+			row := []string{
+				f.Name, strconv.Itoa(f.Assignments), strconv.Itoa(f.Branches),
+				strconv.Itoa(f.Conditions), formatFloat(f.Magnitude),
+			}
+			rows = append(rows, append(row, spanRecord(f.Span)...))
+		}
+	case *tools.OOResult:
+		header = append([]string{"type", "base", "wmc", "dit", "noc", "cbo", "rfc", "lcom4"}, spanHeader...)
+		for _, t := range r.Types {
+			row := []string{
+				t.Name, t.Base, strconv.Itoa(t.WMC), strconv.Itoa(t.DIT), strconv.Itoa(t.NOC),
+				strconv.Itoa(t.CBO), strconv.Itoa(t.RFC), strconv.Itoa(t.LCOM4),
+			}
+			rows = append(rows, append(row, spanRecord(t.Span)...))
+		}
+	case *tools.ControlFlowResult:
+		header = append([]string{"function", "nodes", "edges", "complexity"}, spanHeader...)
+		for _, f := range r.Functions {
+			row := []string{
+				f.Name, strconv.Itoa(len(f.Graph.Nodes)), strconv.Itoa(len(f.Graph.Edges)), strconv.Itoa(f.Complexity),
+			}
+			rows = append(rows, append(row, spanRecord(f.Span)...))
+		}
+	default:
+		return nil, nil, ErrUnknownResult.New(result)
+	}
+	return header, rows, nil
+}
+
+func renderWarnings(w io.Writer, warnings []*tools.Warning) error {
+	for _, warning := range warnings {
+		if _, err := fmt.Fprintf(w, "Warning: %s\n", warning); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var spanHeader = []string{"start_line", "start_col", "start_offset", "end_line", "end_col", "end_offset"}
+
+func spanRecord(s tools.Span) []string {
+	return []string{
+		formatUint(s.StartLine), formatUint(s.StartCol), formatUint(s.StartOffset),
+		formatUint(s.EndLine), formatUint(s.EndCol), formatUint(s.EndOffset),
+	}
+}
+
+var halsteadHeader = []string{
+	"distinct_operators", "distinct_operands", "total_operators", "total_operands",
+	"vocabulary", "length", "volume", "difficulty", "effort", "bugs", "time",
+}
+
+func halsteadRecord(m tools.HalsteadMetrics) []string {
+	return []string{
+		strconv.Itoa(m.DistinctOperators), strconv.Itoa(m.DistinctOperands),
+		strconv.Itoa(m.TotalOperators), strconv.Itoa(m.TotalOperands),
+		strconv.Itoa(m.Vocabulary), strconv.Itoa(m.Length),
+		formatFloat(m.Volume), formatFloat(m.Difficulty), formatFloat(m.Effort),
+		formatFloat(m.Bugs), formatFloat(m.Time),
+	}
+}
+
+// warningsRecord joins the warnings in a single column.
+func warningsRecord(warnings []*tools.Warning) string {
+	messages := make([]string, len(warnings))
+	for i, warning := range warnings {
+		messages[i] = warning.String()
+	}
+	return strings.Join(messages, "; ")
+}
+
+func formatUint(v uint32) string {
+	return strconv.FormatUint(uint64(v), 10)
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
@@ -0,0 +1,26 @@
+package main
+
+import "github.com/bblfsh/tools"
+
+type Signature struct {
+	Common
+	MaxArguments  int `long:"max-arguments" description:"maximum number of arguments of a function, exceeding it makes the command fail"`
+	MaxReturns    int `long:"max-returns" description:"maximum number of return statements of a function, exceeding it makes the command fail"`
+	MaxStatements int `long:"max-statements" description:"maximum number of statements of a function, exceeding it makes the command fail"`
+	MaxLines      int `long:"max-lines" description:"maximum number of lines of a function, exceeding it makes the command fail"`
+}
+
+func (c *Signature) Execute(args []string) error {
+	return c.execute(args, tools.Signature{}, c.check)
+}
+
+func (c *Signature) check(result tools.Result) []*violation {
+	var l limits
+	for i, f := range result.(*tools.SignatureResult).Functions {
+		l.check("arguments", c.MaxArguments, f.Arguments, i, f.Name, f.Span)
+		l.check("returns", c.MaxReturns, f.Returns, i, f.Name, f.Span)
+		l.check("statements", c.MaxStatements, f.Statements, i, f.Name, f.Span)
+		l.check("lines", c.MaxLines, f.Lines, i, f.Name, f.Span)
+	}
+	return l
+}
@@ -7,5 +7,5 @@ type Tokenizer struct {
 }
 
 func (c *Tokenizer) Execute(args []string) error {
-	return c.execute(args, tools.Tokenizer{})
+	return c.execute(args, tools.Tokenizer{}, nil)
 }
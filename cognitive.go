@@ -0,0 +1,188 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"gopkg.in/bblfsh/sdk.v1/uast"
+)
+
+type Cognitive struct{}
+
+// CognitiveData is the cognitive complexity of a function.
+type CognitiveData struct {
+	Name       string `json:"name"`
+	Complexity int    `json:"complexity"`
+	Span
+}
+
+// CognitiveResult is the result of the Cognitive tool.
+type CognitiveResult struct {
+	Functions []*CognitiveData `json:"functions"`
+}
+
+// Analyze returns a *CognitiveResult with the cognitive complexity of every
+// function in the node.
+func (c Cognitive) Analyze(ctx context.Context, n *uast.Node) (Result, error) {
+	return &CognitiveResult{Functions: CognitiveComplexity(n)}, nil
+}
+
+func (cd *CognitiveData) String() string {
+	return fmt.Sprintf("FuncName:%s, Complexity:%d, Position:%s\n", cd.Name, cd.Complexity, cd.Span)
+}
+
+// CognitiveComplexity computes the cognitive complexity of the functions in a
+// *uast.Node, a measure of how hard to understand their control flow is.
+//
+// It follows the SonarSource definition, see:
+// https://www.sonarsource.com/docs/CognitiveComplexity.pdf
+//
+// * if, switch, loops, catch and conditional expressions add one plus their
+// nesting level, and increase the nesting level of their contents, as do the
+// nested functions and lambdas.
+// * else and else if add one, without nesting increments. The if of an else
+// if doesn't add anything else, so a chain of else if isn't nested deeper.
+// * every sequence of like short-circuit boolean operators adds one, so
+// "a && b && c" adds one and "a && b || c" adds two.
+// * break and continue to a label, goto and recursive calls add one.
+//
+// The functions are named and found as in NPathComplexity. The nested
+// functions are also reported on their own, without the nesting level of the
+// function declaring them.
+func CognitiveComplexity(n *uast.Node) []*CognitiveData {
+	var result []*CognitiveData
+	for _, function := range functions(n) {
+		v := &cognitiveVisitor{}
+		if function.decl != nil {
+			v.name = functionName(function.decl)
+		}
+		v.visitChildren(function.body, 0)
+		result = append(result, &CognitiveData{
+			Name:       function.name,
+			Complexity: v.complexity,
+			Span:       function.span(),
+		})
+	}
+	return result
+}
+
+// cognitiveVisitor computes the cognitive complexity of a function.
+type cognitiveVisitor struct {
+	// name is the unqualified name of the function, to find the recursive
+	// calls. It's empty for anonymous functions.
+	name       string
+	complexity int
+}
+
+func (v *cognitiveVisitor) visit(n *uast.Node, nesting int) {
+	switch {
+	case isFunction(n):
+		v.visitChildren(n, nesting+1)
+	case isIfStatement(n):
+		v.complexity += 1 + nesting
+		v.visitIf(n, nesting)
+	case containsRoles(n, []uast.Role{uast.Statement, uast.Switch}, []uast.Role{uast.Case, uast.Default, uast.Body}),
+		containsRoles(n, []uast.Role{uast.Statement, uast.For}, []uast.Role{uast.Body}),
+		containsRoles(n, []uast.Role{uast.Statement, uast.While}, []uast.Role{uast.Body}),
+		containsRoles(n, []uast.Role{uast.Statement, uast.DoWhile}, []uast.Role{uast.Body}),
+		containsRoles(n, []uast.Role{uast.Try, uast.Catch}, []uast.Role{uast.Body}),
+		isConditionalExpr(n):
+		v.complexity += 1 + nesting
+		v.visitChildren(n, nesting+1)
+	case isJump(n):
+		// only the jumps to a label, which is their child, break the flow
+		if containsRoles(n, []uast.Role{uast.Goto}, nil) || len(n.Children) > 0 {
+			v.complexity++
+		}
+	case isBooleanExpr(n):
+		v.visitBooleanExpr(n, nesting)
+	default:
+		if v.isRecursiveCall(n) {
+			v.complexity++
+		}
+		v.visitChildren(n, nesting)
+	}
+}
+
+func (v *cognitiveVisitor) visitChildren(n *uast.Node, nesting int) {
+	for _, child := range n.Children {
+		v.visit(child, nesting)
+	}
+}
+
+// visitIf visits an if statement, which has already been counted. Its
+// condition and branches are nested, while the else and else if add one
+// without increasing the nesting level.
+func (v *cognitiveVisitor) visitIf(n *uast.Node, nesting int) {
+	for _, child := range n.Children {
+		if !containsRoles(child, []uast.Role{uast.If, uast.Else}, nil) {
+			v.visit(child, nesting+1)
+			continue
+		}
+
+		v.complexity++
+		if elif := elseIf(child); elif != nil {
+			v.visitIf(elif, nesting)
+		} else {
+			v.visit(child, nesting+1)
+		}
+	}
+}
+
+func (v *cognitiveVisitor) visitBooleanExpr(n *uast.Node, nesting int) {
+	var ops []uast.Role
+	var operands []*uast.Node
+	booleanSequence(n, &ops, &operands)
+
+	// an operator missing its operands is still a sequence
+	sequences := 1
+	for i := 1; i < len(ops); i++ {
+		if ops[i] != ops[i-1] {
+			sequences++
+		}
+	}
+	v.complexity += sequences
+
+	for _, operand := range operands {
+		v.visit(operand, nesting)
+	}
+}
+
+// isRecursiveCall reports whether n is a call to the function being
+// analyzed, by the name of the callee. The calls passed as arguments, like
+// the inner one of f(f(x)), are calls too.
+func (v *cognitiveVisitor) isRecursiveCall(n *uast.Node) bool {
+	if v.name == "" || !containsRoles(n, []uast.Role{uast.Call}, []uast.Role{uast.Callee, uast.Receiver}) {
+		return false
+	}
+	for _, callee := range childrenOfRoles(n, []uast.Role{uast.Call, uast.Callee}, nil) {
+		name := joinedTokens(callee)
+		// methods called on an object, like this.name
+		name = name[strings.LastIndex(name, ".")+1:]
+		if name == v.name {
+			return true
+		}
+	}
+	return false
+}
+
+// isIfStatement reports whether n is an if statement, including the ones
+// in the branches of another if.
+func isIfStatement(n *uast.Node) bool {
+	return containsRoles(n, []uast.Role{uast.Statement, uast.If}, nil) &&
+		countChildrenOfRoles(n, []uast.Role{uast.If, uast.Condition}, nil) > 0
+}
+
+// elseIf returns the if statement of an else if, or nil if the else is not
+// followed by an if. The else is either the if statement itself or a node
+// with it as its only child, like the elif of Python, but not a block.
+func elseIf(n *uast.Node) *uast.Node {
+	if isIfStatement(n) {
+		return n
+	}
+	if len(n.Children) == 1 && !containsRoles(n, []uast.Role{uast.Block}, nil) && isIfStatement(n.Children[0]) {
+		return n.Children[0]
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package tools
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/bblfsh/sdk.v1/uast"
+)
+
+func TestCognitiveFixture(t *testing.T) {
+	require := require.New(t)
+
+	// the fixture is hand-written, see fixtures/README.md
+	result, err := Cognitive{}.Analyze(context.Background(), fixtureUAST(t, "fixtures/npath/cognitive.java.json"))
+	require.NoError(err)
+
+	var names []string
+	var complexities []int
+	for _, f := range result.(*CognitiveResult).Functions {
+		names = append(names, f.Name)
+		complexities = append(complexities, f.Complexity)
+	}
+	require.Equal([]string{"Code.sumOfPrimes(int)", "Code.words(int)", "Code.fact(int)", "Code.sign(int)"}, names)
+	require.Equal([]int{7, 1, 6, 7}, complexities)
+	require.Equal(uint32(2), result.(*CognitiveResult).Functions[0].StartLine)
+}
+
+// cognitiveBody returns a module declaring a function named "f" with the
+// statements as its body.
+func cognitiveBody(statements ...*uast.Node) *uast.Node {
+	return &uast.Node{InternalType: "module", Children: []*uast.Node{
+		{InternalType: "func", Roles: []uast.Role{uast.Function, uast.Declaration}, Children: []*uast.Node{
+			{InternalType: "name", Roles: []uast.Role{uast.Function, uast.Name}, Token: "f"},
+			{InternalType: "body", Roles: []uast.Role{uast.Function, uast.Body}, Children: statements},
+		}},
+	}}
+}
+
+func TestCognitiveBooleanSequences(t *testing.T) {
+	and := func(children ...*uast.Node) *uast.Node {
+		return &uast.Node{InternalType: "and", Roles: []uast.Role{uast.Expression, uast.Operator, uast.Boolean, uast.And}, Children: children}
+	}
+	or := func(children ...*uast.Node) *uast.Node {
+		return &uast.Node{InternalType: "or", Roles: []uast.Role{uast.Expression, uast.Operator, uast.Boolean, uast.Or}, Children: children}
+	}
+	not := func(child *uast.Node) *uast.Node {
+		return &uast.Node{InternalType: "not", Roles: []uast.Role{uast.Expression, uast.Operator, uast.Boolean, uast.Not}, Children: []*uast.Node{child}}
+	}
+	expr := func(children ...*uast.Node) *uast.Node {
+		return &uast.Node{InternalType: "expr", Roles: []uast.Role{uast.Expression}, Children: children}
+	}
+	id := func(token string) *uast.Node {
+		return &uast.Node{InternalType: "id", Roles: []uast.Role{uast.Expression, uast.Identifier}, Token: token}
+	}
+
+	cases := []struct {
+		name   string
+		n      *uast.Node
+		expect int
+	}{
+		{"a && b && c", and(and(id("a"), id("b")), id("c")), 1},
+		{"a && b || c", or(and(id("a"), id("b")), id("c")), 2},
+		{"a && (b && c)", and(id("a"), expr(and(id("b"), id("c")))), 1},
+		{"a && !(b && c)", and(id("a"), not(and(id("b"), id("c")))), 2},
+		{"python a and b and c", expr(and(), id("a"), id("b"), id("c")), 1},
+		{"python a or b and c or d", expr(or(), id("a"), expr(and(), id("b"), id("c")), id("d")), 3},
+		{"lisp (and a b (and c d))", expr(and(), id("a"), id("b"), expr(and(), id("c"), id("d"))), 1},
+		{"flat a && b || c", expr(id("a"), and(), id("b"), or(), id("c")), 2},
+	}
+
+	for _, c := range cases {
+		result := CognitiveComplexity(cognitiveBody(c.n))
+		require.Equal(t, c.expect, result[0].Complexity, c.name)
+	}
+}
+
+func TestCognitiveNesting(t *testing.T) {
+	require := require.New(t)
+
+	cond := func() *uast.Node {
+		return &uast.Node{InternalType: "cond", Roles: []uast.Role{uast.Expression, uast.If, uast.Condition}}
+	}
+	ifStmt := func(children ...*uast.Node) *uast.Node {
+		return &uast.Node{InternalType: "if", Roles: []uast.Role{uast.Statement, uast.If}, Children: append([]*uast.Node{cond()}, children...)}
+	}
+	then := func(children ...*uast.Node) *uast.Node {
+		return &uast.Node{InternalType: "then", Roles: []uast.Role{uast.If, uast.Then, uast.Body}, Children: children}
+	}
+	// the orelse of python, a list wrapping the elif or the else statements
+	orElse := func(children ...*uast.Node) *uast.Node {
+		return &uast.Node{InternalType: "orelse", Roles: []uast.Role{uast.If, uast.Else}, Children: children}
+	}
+	while := func(children ...*uast.Node) *uast.Node {
+		return &uast.Node{InternalType: "while", Roles: []uast.Role{uast.Statement, uast.While}, Children: []*uast.Node{
+			{InternalType: "cond", Roles: []uast.Role{uast.While, uast.Condition}},
+			{InternalType: "body", Roles: []uast.Role{uast.While, uast.Body}, Children: children},
+		}}
+	}
+	lambda := func(children ...*uast.Node) *uast.Node {
+		return &uast.Node{InternalType: "Lambda", Roles: []uast.Role{uast.Expression}, Children: children}
+	}
+	call := func(callee ...*uast.Node) *uast.Node {
+		return &uast.Node{InternalType: "call", Roles: []uast.Role{uast.Expression, uast.Call}, Children: callee}
+	}
+	// an argument of a call, with the roles of the node passed
+	argument := func(n *uast.Node) *uast.Node {
+		n.Roles = append(n.Roles, uast.Call, uast.Argument, uast.Positional)
+		return n
+	}
+	statement := &uast.Node{InternalType: "statement", Roles: []uast.Role{uast.Statement}}
+
+	cases := []struct {
+		name   string
+		n      *uast.Node
+		expect []int
+	}{
+		// if: +1, elif: +1, elif: +1, else: +1
+		{"elif chain", cognitiveBody(ifStmt(then(statement), orElse(ifStmt(then(statement), orElse(ifStmt(then(statement), orElse(statement))))))), []int{4}},
+		// if: +1, nested if in the else: +1 +2
+		{"if in else", cognitiveBody(ifStmt(then(statement), orElse(statement, ifStmt(then(statement))))), []int{4}},
+		// while: +1, if: +2, while: +3
+		{"nested loops", cognitiveBody(while(ifStmt(then(while(statement))))), []int{6}},
+		// lambda: nesting, if: +2; the lambda on its own: if: +1
+		{"lambda", cognitiveBody(lambda(ifStmt(then(statement)))), []int{2, 1}},
+		// goto: +1, unlabeled break: 0
+		{"jumps", cognitiveBody(
+			&uast.Node{InternalType: "goto", Roles: []uast.Role{uast.Statement, uast.Goto}},
+			&uast.Node{InternalType: "break", Roles: []uast.Role{uast.Statement, uast.Break}},
+		), []int{1}},
+		// f(), this.f(): +1 each, g(): 0
+		{"recursion", cognitiveBody(
+			call(&uast.Node{InternalType: "callee", Roles: []uast.Role{uast.Call, uast.Callee}, Token: "f"}),
+			call(&uast.Node{InternalType: "callee", Roles: []uast.Role{uast.Call, uast.Callee}, Children: []*uast.Node{
+				{InternalType: "this", Token: "this"},
+				{InternalType: "name", Token: "f"},
+			}}),
+			call(&uast.Node{InternalType: "callee", Roles: []uast.Role{uast.Call, uast.Callee}, Token: "g"}),
+		), []int{2}},
+		// f(f(x)): +2, g(f(x)): +1
+		{"recursion in arguments", cognitiveBody(
+			call(
+				&uast.Node{InternalType: "callee", Roles: []uast.Role{uast.Call, uast.Callee}, Token: "f"},
+				argument(call(
+					&uast.Node{InternalType: "callee", Roles: []uast.Role{uast.Call, uast.Callee}, Token: "f"},
+					argument(&uast.Node{InternalType: "id", Roles: []uast.Role{uast.Expression, uast.Identifier}, Token: "x"}),
+				)),
+			),
+			call(
+				&uast.Node{InternalType: "callee", Roles: []uast.Role{uast.Call, uast.Callee}, Token: "g"},
+				argument(call(
+					&uast.Node{InternalType: "callee", Roles: []uast.Role{uast.Call, uast.Callee}, Token: "f"},
+					argument(&uast.Node{InternalType: "id", Roles: []uast.Role{uast.Expression, uast.Identifier}, Token: "x"}),
+				)),
+			),
+		), []int{3}},
+	}
+
+	for _, c := range cases {
+		var complexities []int
+		for _, f := range CognitiveComplexity(c.n) {
+			complexities = append(complexities, f.Complexity)
+		}
+		require.Equal(c.expect, complexities, c.name)
+	}
+}
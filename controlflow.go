@@ -0,0 +1,66 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bblfsh/tools/cfg"
+	"gopkg.in/bblfsh/sdk.v1/uast"
+)
+
+type ControlFlow struct{}
+
+// ControlFlowData is the control flow graph of a function.
+type ControlFlowData struct {
+	Name string `json:"name"`
+	// Complexity is the cyclomatic complexity of the graph.
+	Complexity int        `json:"complexity"`
+	Graph      *cfg.Graph `json:"graph"`
+	Span
+}
+
+// ControlFlowResult is the result of the ControlFlow tool.
+type ControlFlowResult struct {
+	Functions []*ControlFlowData `json:"functions"`
+}
+
+// Analyze returns a *ControlFlowResult with the control flow graph of every
+// function in the node.
+func (c ControlFlow) Analyze(ctx context.Context, n *uast.Node) (Result, error) {
+	return &ControlFlowResult{Functions: ControlFlowGraphs(n)}, nil
+}
+
+func (cd *ControlFlowData) String() string {
+	return fmt.Sprintf("FuncName:%s, Nodes:%d, Edges:%d, Complexity:%d, Position:%s\n",
+		cd.Name, len(cd.Graph.Nodes), len(cd.Graph.Edges), cd.Complexity, cd.Span)
+}
+
+// ControlFlowGraphs builds the control flow graph of the functions in a
+// *uast.Node, see the cfg package for how the statements are told apart.
+// The nested functions and types are not part of the graph of the function
+// declaring them, they have their own.
+//
+// The functions are found as in NPathComplexity.
+func ControlFlowGraphs(n *uast.Node) []*ControlFlowData {
+	var result []*ControlFlowData
+	for _, function := range functions(n) {
+		g := controlFlowGraph(function.body)
+		result = append(result, &ControlFlowData{
+			Name:       function.name,
+			Complexity: g.Cyclomatic(),
+			Graph:      g,
+			Span:       function.span(),
+		})
+	}
+	return result
+}
+
+func controlFlowGraph(body *uast.Node) *cfg.Graph {
+	return cfg.Build(body, isNestedDeclaration)
+}
+
+// isNestedDeclaration reports whether n is a function or type declared
+// inside a function.
+func isNestedDeclaration(n *uast.Node) bool {
+	return isFunction(n) || isTypeDeclaration(n)
+}
@@ -0,0 +1,82 @@
+package tools
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestControlFlowFixtures(t *testing.T) {
+	require := require.New(t)
+
+	cases := []struct {
+		file   string
+		expect []int
+	}{
+		// the graph doesn't count the boolean operators nor the conditional
+		// expressions, nor the Then and Else blocks as the default profile
+		{"fixtures/npath/cognitive.java.json", []int{4, 3, 3, 3}},
+		{"fixtures/npath/ifelse.java.json", []int{2}},
+		{"fixtures/npath/do_while.java.json", []int{2}},
+		{"fixtures/npath/switch.java.json", []int{4}},
+		{"fixtures/npath/try.java.json", []int{3}},
+		{"fixtures/npath/jumps.java.json", []int{4, 7, 3}},
+		{"fixtures/npath/ternary.py.json", []int{1, 1, 2}},
+	}
+
+	for _, c := range cases {
+		result, err := ControlFlow{}.Analyze(context.Background(), fixtureUAST(t, c.file))
+		require.NoError(err)
+
+		var got []int
+		for _, f := range result.(*ControlFlowResult).Functions {
+			require.Equal(f.Graph.Cyclomatic(), f.Complexity)
+			got = append(got, f.Complexity)
+		}
+		require.Equal(c.expect, got, c.file)
+	}
+}
+
+func TestControlFlowJumps(t *testing.T) {
+	require := require.New(t)
+
+	f := ControlFlowGraphs(fixtureUAST(t, "fixtures/npath/jumps.java.json"))[0]
+	labels := make(map[int]string)
+	for _, n := range f.Graph.Nodes {
+		labels[n.ID] = n.Label
+	}
+	var jumps []string
+	for _, e := range f.Graph.Edges {
+		switch labels[e.From] {
+		case "ContinueStatement", "BreakStatement":
+			jumps = append(jumps, labels[e.From]+"->"+labels[e.To])
+		}
+	}
+	// continue goes back to the loop and "break outer" leaves it
+	sort.Strings(jumps)
+	require.Equal([]string{
+		"BreakStatement->ReturnStatement",
+		"ContinueStatement->ForStatement",
+	}, jumps)
+}
+
+func TestCyclomaticGraph(t *testing.T) {
+	require := require.New(t)
+
+	n := fixtureUAST(t, "fixtures/npath/jumps.java.json")
+	result, err := CyclomaticComplexity{Graph: true}.Analyze(context.Background(), n)
+	require.NoError(err)
+
+	var got [][]int
+	for _, f := range result.(*CyclomaticResult).Functions {
+		got = append(got, []int{f.Complexity, f.GraphComplexity})
+	}
+	require.Equal([][]int{{8, 4}, {11, 7}, {5, 3}}, got)
+
+	// not computed unless asked for
+	result, err = CyclomaticComplexity{}.Analyze(context.Background(), n)
+	require.NoError(err)
+	require.Zero(result.(*CyclomaticResult).Functions[0].GraphComplexity)
+}
@@ -1,6 +1,7 @@
 package tools
 
 import (
+	"context"
 	"fmt"
 
 	"gopkg.in/bblfsh/sdk.v1/uast"
@@ -16,13 +17,22 @@ import (
 // counting one + one of the following UAST Roles if present on any children:
 // * Statement, If | Case | For | While | DoWhile | Continue
 // * Try, Catch
-// * Operator, Boolean
 // * Goto
-// Important: since some languages allow for code defined
-// outside function definitions, this won't check that the Node has the roles Function, Declaration
-// so the user should check that if the intended use is calculating the complexity of a function/method.
-// If the children contain more than one function definitions, the value will not be averaged between
-// the total number of function declarations but given as a total.
+// plus one for every condition of a short-circuit boolean expression, that is, one less than the
+// number of operands of each and/or.
+// Since they don't agree on what adds complexity, the rules are configurable with a CyclomaticProfile,
+// the built-in ones follow some of these tools and a custom one can be read from a rule file. The
+// default profile uses the roles listed above.
+//
+// The complexity is reported for every function or method found with the same logic used by
+// NPathComplexity, and also for the whole node. Since some languages allow for code defined
+// outside function definitions, the complexity of the whole node is not averaged between the
+// total number of function declarations but given as a total.
+//
+// With Graph, the complexity of every function is also computed from its control flow graph, built
+// as in ControlFlow, as E - N + 2P. Since the graph doesn't split the expressions, it doesn't count
+// the short-circuit boolean operators nor the conditional expressions, but it's a way to check what
+// the rules of a profile count.
 //
 // Some practical implementations counting tokens in the code. They sometimes differ; for example
 // some of them count the switch "default" as an incrementor, some consider all return values minus the
@@ -36,49 +46,113 @@ import (
 // Go: https://github.com/fzipp/gocyclo/blob/master/gocyclo.go#L214
 // SonarQube (include rules for many languages): https://docs.sonarqube.org/display/SONAR/Metrics+-+Complexity
 //
-// IMPORTANT DISCLAIMER: McCabe definition specifies clearly that boolean operations should increment the
-// count in 1 for every boolean element when the language if the language evaluates conditions in
-// short-circuit.  Unfortunately in the current version of the UAST we don't specify these language invariants
-// and also we still haven't defined how we are going to represent the boolean expressions (which also would
-// need a tree transformation process in the pipeline that we lack) so lacking a better way of detecting for
-// all  languages what symbols or literals are part of a boolean expression we count the boolean operators
-// themselves which should work for short-circuit infix languages but not prefix or infix languages that can
-// evaluate more than two items with a single operator.  (FIXME when both things are solved in the UAST
-// definition and the SDK).
-
-type CyclomaticComplexity struct{}
+// McCabe definition specifies that boolean operations should increment the count in 1 for every
+// boolean element when the language evaluates conditions in short-circuit. The operands are counted
+// instead of the operator nodes, so "a && b && c" adds two whether it's represented as nested binary
+// operators, as a single operator with three operands like Python "a and b and c", or as a prefix
+// expression like Lisp "(and a b c)". Non short-circuit boolean operators, like xor, only add
+// complexity in the profiles with a rule for them.
+
+type CyclomaticComplexity struct {
+	// Profile has the rules deciding which nodes add complexity, the default
+	// profile is used if it's nil.
+	Profile *CyclomaticProfile
+	// Graph also computes the complexity of every function from its control
+	// flow graph, as E - N + 2P, to check the count of the profile.
+	Graph bool
+}
+
+// CyclomaticData is the cyclomatic complexity of a function.
+type CyclomaticData struct {
+	Name       string `json:"name"`
+	Complexity int    `json:"complexity"`
+	// GraphComplexity is the complexity of the control flow graph of the
+	// function, zero if it's not computed.
+	GraphComplexity int `json:"graph_complexity,omitempty"`
+	Span
+}
+
+// CyclomaticResult is the result of the CyclomaticComplexity tool.
+type CyclomaticResult struct {
+	// Complexity is the total complexity of the analyzed node.
+	Complexity int               `json:"complexity"`
+	Functions  []*CyclomaticData `json:"functions"`
+}
 
 func (cc CyclomaticComplexity) Exec(n *uast.Node) error {
-	result := cyclomaticComplexity(n)
+	result := cc.profile().complexity(n)
 	fmt.Println("Cyclomatic Complexity = ", result)
 	return nil
 }
 
+// Analyze returns a *CyclomaticResult with the cyclomatic complexity of the node
+// and of every function in it.
+func (cc CyclomaticComplexity) Analyze(ctx context.Context, n *uast.Node) (Result, error) {
+	p := cc.profile()
+	return &CyclomaticResult{
+		Complexity: p.complexity(n),
+		Functions:  p.complexityOfFunctions(n, cc.Graph),
+	}, nil
+}
+
+func (cc CyclomaticComplexity) profile() *CyclomaticProfile {
+	if cc.Profile == nil {
+		return cyclomaticProfiles[CyclomaticDefault]
+	}
+	return cc.Profile
+}
+
+func (cd *CyclomaticData) String() string {
+	if cd.GraphComplexity > 0 {
+		return fmt.Sprintf("FuncName:%s, Complexity:%d, GraphComplexity:%d, Position:%s\n",
+			cd.Name, cd.Complexity, cd.GraphComplexity, cd.Span)
+	}
+	return fmt.Sprintf("FuncName:%s, Complexity:%d, Position:%s\n", cd.Name, cd.Complexity, cd.Span)
+}
+
+// CyclomaticComplexityOfFunctions returns the cyclomatic complexity of every function
+// in the node, with the default profile.
+func CyclomaticComplexityOfFunctions(n *uast.Node) []*CyclomaticData {
+	return cyclomaticProfiles[CyclomaticDefault].complexityOfFunctions(n, false)
+}
+
+func (p *CyclomaticProfile) complexityOfFunctions(n *uast.Node, graph bool) []*CyclomaticData {
+	var result []*CyclomaticData
+	for _, function := range functions(n) {
+		data := &CyclomaticData{
+			Name:       function.name,
+			Complexity: p.complexity(function.body),
+			Span:       function.span(),
+		}
+		if graph {
+			data.GraphComplexity = controlFlowGraph(function.body).Cyclomatic()
+		}
+		result = append(result, data)
+	}
+	return result
+}
+
 func cyclomaticComplexity(n *uast.Node) int {
+	return cyclomaticProfiles[CyclomaticDefault].complexity(n)
+}
+
+func (p *CyclomaticProfile) complexity(n *uast.Node) int {
 	complexity := 1
 
 	iter := uast.NewOrderPathIter(uast.NewPath(n))
 
 	for {
-		p := iter.Next()
-		if p.IsEmpty() {
+		path := iter.Next()
+		if path.IsEmpty() {
 			break
 		}
-		n := p.Node()
-		roles := make(map[uast.Role]bool)
-		for _, r := range n.Roles {
-			roles[r] = true
-		}
-		if addsComplexity(roles) {
+		node := path.Node()
+		if p.addsComplexity(node) {
 			complexity++
 		}
+		if p.ShortCircuit {
+			complexity += shortCircuitIncrement(node)
+		}
 	}
 	return complexity
 }
-
-func addsComplexity(roles map[uast.Role]bool) bool {
-	return roles[uast.Statement] && (roles[uast.If] || roles[uast.Case] || roles[uast.For] || roles[uast.While] || roles[uast.DoWhile] || roles[uast.Continue]) ||
-		roles[uast.Try] && roles[uast.Catch] ||
-		roles[uast.Operator] && roles[uast.Boolean] ||
-		roles[uast.Goto]
-}
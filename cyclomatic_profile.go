@@ -0,0 +1,263 @@
+package tools
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"strings"
+
+	"gopkg.in/bblfsh/sdk.v1/uast"
+	"gopkg.in/src-d/go-errors.v1"
+)
+
+var (
+	ErrUnknownRole       = errors.NewKind("unknown role %s")
+	ErrInvalidRules      = errors.NewKind("invalid cyclomatic rules: %s")
+	ErrUnknownCyclomatic = errors.NewKind("unknown cyclomatic profile %s")
+)
+
+// CyclomaticRule is a combination of roles adding one to the cyclomatic
+// complexity. A node matches the rule if it has all the Roles and none of the
+// NotRoles, one of its children has all the ChildRoles and its internal type
+// is one of the Types. Empty fields always match.
+type CyclomaticRule struct {
+	Roles      []uast.Role
+	NotRoles   []uast.Role
+	ChildRoles []uast.Role
+	// Types are the internal types of the nodes, used for the ones that
+	// some drivers don't annotate with roles, like conditional expressions.
+	Types []string
+}
+
+func (r *CyclomaticRule) matches(n *uast.Node) bool {
+	if len(r.Types) > 0 && !containsString(r.Types, n.InternalType) {
+		return false
+	}
+	if !containsRoles(n, r.Roles, r.NotRoles) {
+		return false
+	}
+	return len(r.ChildRoles) == 0 || countChildrenOfRoles(n, r.ChildRoles, nil) > 0
+}
+
+// jsonCyclomaticRule is a CyclomaticRule in a rule file, with the roles by
+// name, like "Statement" or "If".
+type jsonCyclomaticRule struct {
+	Roles      []string `json:"roles,omitempty"`
+	NotRoles   []string `json:"not,omitempty"`
+	ChildRoles []string `json:"child,omitempty"`
+	Types      []string `json:"types,omitempty"`
+}
+
+func (r *CyclomaticRule) UnmarshalJSON(data []byte) error {
+	var aux jsonCyclomaticRule
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	var err error
+	if r.Roles, err = rolesByName(aux.Roles); err != nil {
+		return err
+	}
+	if r.NotRoles, err = rolesByName(aux.NotRoles); err != nil {
+		return err
+	}
+	if r.ChildRoles, err = rolesByName(aux.ChildRoles); err != nil {
+		return err
+	}
+	r.Types = aux.Types
+	return nil
+}
+
+func (r CyclomaticRule) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonCyclomaticRule{
+		Roles:      roleNames(r.Roles),
+		NotRoles:   roleNames(r.NotRoles),
+		ChildRoles: roleNames(r.ChildRoles),
+		Types:      r.Types,
+	})
+}
+
+// CyclomaticProfile is the set of rules used to compute the cyclomatic
+// complexity: every node matching any of them adds one.
+type CyclomaticProfile struct {
+	Name  string           `json:"name"`
+	Rules []CyclomaticRule `json:"rules"`
+	// ShortCircuit adds the conditions of the short-circuit boolean
+	// expressions, one less than the operands of every and/or, which can't
+	// be expressed as a rule matching single nodes.
+	ShortCircuit bool `json:"short_circuit,omitempty"`
+}
+
+// ReadCyclomaticProfile reads a profile from a JSON rule file like:
+//
+//	{
+//	  "name": "custom",
+//	  "rules": [
+//	    {"roles": ["Statement", "If"], "child": ["If", "Condition"]},
+//	    {"roles": ["Statement", "Switch", "Case"], "not": ["Body"]},
+//	    {"types": ["ConditionalExpression"]}
+//	  ],
+//	  "short_circuit": true
+//	}
+func ReadCyclomaticProfile(r io.Reader) (*CyclomaticProfile, error) {
+	p := &CyclomaticProfile{}
+	if err := json.NewDecoder(r).Decode(p); err != nil {
+		return nil, ErrInvalidRules.New(err)
+	}
+	if len(p.Rules) == 0 && !p.ShortCircuit {
+		return nil, ErrInvalidRules.New("no rules")
+	}
+	for _, rule := range p.Rules {
+		if len(rule.Roles) == 0 && len(rule.Types) == 0 {
+			return nil, ErrInvalidRules.New("every rule needs roles or types")
+		}
+	}
+	return p, nil
+}
+
+func (p *CyclomaticProfile) addsComplexity(n *uast.Node) bool {
+	for i := range p.Rules {
+		if p.Rules[i].matches(n) {
+			return true
+		}
+	}
+	return false
+}
+
+// Names of the cyclomatic profiles.
+const (
+	CyclomaticDefault = "default"
+	CyclomaticPMD     = "pmd"
+	CyclomaticSonar   = "sonar"
+	CyclomaticGocyclo = "gocyclo"
+	CyclomaticMcCabe  = "mccabe-strict"
+)
+
+// Decision points shared by the profiles. Unlike the default profile, they
+// don't count the blocks and statements annotated as the branches or bodies
+// of the statements, only the statements themselves.
+var (
+	ifRule      = CyclomaticRule{Roles: []uast.Role{uast.Statement, uast.If}, ChildRoles: []uast.Role{uast.If, uast.Condition}}
+	forRule     = CyclomaticRule{Roles: []uast.Role{uast.Statement, uast.For}, ChildRoles: []uast.Role{uast.For, uast.Body}}
+	whileRule   = CyclomaticRule{Roles: []uast.Role{uast.Statement, uast.While}, ChildRoles: []uast.Role{uast.While, uast.Condition}}
+	doWhileRule = CyclomaticRule{Roles: []uast.Role{uast.Statement, uast.DoWhile}, ChildRoles: []uast.Role{uast.DoWhile, uast.Condition}}
+	caseRule    = CyclomaticRule{Roles: []uast.Role{uast.Statement, uast.Switch, uast.Case}, NotRoles: []uast.Role{uast.Body}}
+	catchRule   = CyclomaticRule{Roles: []uast.Role{uast.Try, uast.Catch}, NotRoles: []uast.Role{uast.Body}}
+	xorRule     = CyclomaticRule{Roles: []uast.Role{uast.Operator, uast.Boolean, uast.Xor}}
+	// conditional expressions, by roles and by the internal types used by
+	// the drivers not annotating them
+	ternaryRoleRule = CyclomaticRule{
+		Roles:      []uast.Role{uast.Expression, uast.If},
+		NotRoles:   []uast.Role{uast.Statement},
+		ChildRoles: []uast.Role{uast.If, uast.Condition},
+	}
+	ternaryTypeRule = CyclomaticRule{Types: conditionalTypeNames()}
+)
+
+// cyclomaticProfiles are the built-in profiles, matching the rules of other
+// tools as far as they can be expressed with roles.
+var cyclomaticProfiles = map[string]*CyclomaticProfile{
+	// The rules used before the profiles, kept as the default for
+	// compatibility. It counts the branches and bodies annotated as
+	// statements, so the complexity is usually higher than in other tools.
+	CyclomaticDefault: {Name: CyclomaticDefault, Rules: []CyclomaticRule{
+		{Roles: []uast.Role{uast.Statement, uast.If}},
+		{Roles: []uast.Role{uast.Statement, uast.Case}},
+		{Roles: []uast.Role{uast.Statement, uast.For}},
+		{Roles: []uast.Role{uast.Statement, uast.While}},
+		{Roles: []uast.Role{uast.Statement, uast.DoWhile}},
+		{Roles: []uast.Role{uast.Statement, uast.Continue}},
+		{Roles: []uast.Role{uast.Try, uast.Catch}},
+		{Roles: []uast.Role{uast.Goto}},
+	}, ShortCircuit: true},
+	// PMD: if, loops, case labels (not default), catch, ternary and the
+	// short-circuit boolean operators. PMD doesn't count the fall-through
+	// labels nor the operators out of conditions, which can't be told apart
+	// by their roles.
+	CyclomaticPMD: {Name: CyclomaticPMD, Rules: []CyclomaticRule{
+		ifRule, forRule, whileRule, doWhileRule, caseRule, catchRule,
+		ternaryRoleRule, ternaryTypeRule,
+	}, ShortCircuit: true},
+	// SonarQube: like PMD, but catch doesn't add complexity.
+	CyclomaticSonar: {Name: CyclomaticSonar, Rules: []CyclomaticRule{
+		ifRule, forRule, whileRule, doWhileRule, caseRule,
+		ternaryRoleRule, ternaryTypeRule,
+	}, ShortCircuit: true},
+	// gocyclo: if, loops (the for of Go), case labels and the short-circuit
+	// boolean operators.
+	CyclomaticGocyclo: {Name: CyclomaticGocyclo, Rules: []CyclomaticRule{
+		ifRule, forRule, whileRule, doWhileRule, caseRule,
+	}, ShortCircuit: true},
+	// McCabe counts every condition of a compound predicate, so all the
+	// binary boolean operators add complexity, short-circuit or not.
+	CyclomaticMcCabe: {Name: CyclomaticMcCabe, Rules: []CyclomaticRule{
+		ifRule, forRule, whileRule, doWhileRule, caseRule, catchRule,
+		ternaryRoleRule, ternaryTypeRule, xorRule,
+	}, ShortCircuit: true},
+}
+
+// CyclomaticProfileByName returns one of the built-in profiles.
+func CyclomaticProfileByName(name string) (*CyclomaticProfile, error) {
+	p, ok := cyclomaticProfiles[name]
+	if !ok {
+		return nil, ErrUnknownCyclomatic.New(name)
+	}
+	return p, nil
+}
+
+// CyclomaticProfileNames returns the names of the built-in profiles, sorted.
+func CyclomaticProfileNames() []string {
+	var names []string
+	for name := range cyclomaticProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// roleByName maps the names of the roles to their values.
+var roleByName = make(map[string]uast.Role)
+
+func init() {
+	for r := uast.Role(0); !strings.HasPrefix(r.String(), "Role("); r++ {
+		roleByName[r.String()] = r
+	}
+}
+
+func rolesByName(names []string) ([]uast.Role, error) {
+	var result []uast.Role
+	for _, name := range names {
+		r, ok := roleByName[name]
+		if !ok {
+			return nil, ErrUnknownRole.New(name)
+		}
+		result = append(result, r)
+	}
+	return result, nil
+}
+
+func roleNames(rs []uast.Role) []string {
+	var names []string
+	for _, r := range rs {
+		names = append(names, r.String())
+	}
+	return names
+}
+
+func conditionalTypeNames() []string {
+	var names []string
+	for name := range conditionalTypes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
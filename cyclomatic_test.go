@@ -1,9 +1,15 @@
 package tools
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	"gopkg.in/bblfsh/sdk.v1/protocol"
 	"gopkg.in/bblfsh/sdk.v1/uast"
 )
 
@@ -30,3 +36,137 @@ func TestCyclomaticComplexity(t *testing.T) {
 			}}}
 	require.Equal(cyclomaticComplexity(n), 6)
 }
+
+func TestCyclomaticComplexityOfFunctions(t *testing.T) {
+	require := require.New(t)
+	ifStmt := &uast.Node{InternalType: "if", Roles: []uast.Role{uast.Statement, uast.If}, Children: []*uast.Node{
+		{InternalType: "opOr", Roles: []uast.Role{uast.Operator, uast.Boolean, uast.Or}},
+	}}
+	n := &uast.Node{InternalType: "module", Children: []*uast.Node{
+		{InternalType: "func1", Roles: []uast.Role{uast.Function, uast.Declaration}, Children: []*uast.Node{
+			{InternalType: "name1", Roles: []uast.Role{uast.Function, uast.Name}, Token: "foo"},
+			{InternalType: "body1", Roles: []uast.Role{uast.Function, uast.Body}, Children: []*uast.Node{ifStmt}},
+		}},
+		{InternalType: "func2", Roles: []uast.Role{uast.Function, uast.Declaration}, Children: []*uast.Node{
+			{InternalType: "name2", Roles: []uast.Role{uast.Function, uast.Name}, Token: "bar"},
+			{InternalType: "body2", Roles: []uast.Role{uast.Function, uast.Body}},
+		}},
+		{InternalType: "abstract", Roles: []uast.Role{uast.Function, uast.Declaration}, Children: []*uast.Node{
+			{InternalType: "name3", Roles: []uast.Role{uast.Function, uast.Name}, Token: "baz"},
+		}},
+	}}
+
+	expect := []*CyclomaticData{
+		{Name: "foo", Complexity: 3},
+		{Name: "bar", Complexity: 1},
+	}
+	require.Equal(expect, CyclomaticComplexityOfFunctions(n))
+	require.Equal(3, cyclomaticComplexity(n))
+}
+
+func fixtureUAST(t *testing.T, name string) *uast.Node {
+	f, err := os.Open(name)
+	require.NoError(t, err)
+	defer f.Close()
+
+	res := &protocol.ParseResponse{}
+	require.NoError(t, json.NewDecoder(f).Decode(res))
+	return res.UAST
+}
+
+func TestCyclomaticProfiles(t *testing.T) {
+	require := require.New(t)
+
+	cases := []struct {
+		file    string
+		profile string
+		expect  []int
+	}{
+		{"fixtures/npath/ternary.java.json", CyclomaticDefault, []int{1, 1, 4}},
+		{"fixtures/npath/ternary.java.json", CyclomaticPMD, []int{2, 3, 4}},
+		{"fixtures/npath/ternary.java.json", CyclomaticGocyclo, []int{1, 1, 3}},
+		{"fixtures/npath/jumps.java.json", CyclomaticDefault, []int{8, 11, 5}},
+		{"fixtures/npath/jumps.java.json", CyclomaticSonar, []int{4, 8, 3}},
+		{"fixtures/npath/jumps.java.json", CyclomaticGocyclo, []int{4, 7, 3}},
+		{"fixtures/npath/try.java.json", CyclomaticPMD, []int{3}},
+		{"fixtures/npath/try.java.json", CyclomaticSonar, []int{2}},
+		{"fixtures/npath/ternary.js.json", CyclomaticMcCabe, []int{2, 3, 2}},
+		{"fixtures/npath/ternary.py.json", CyclomaticMcCabe, []int{2, 3, 4}},
+	}
+
+	for _, c := range cases {
+		profile, err := CyclomaticProfileByName(c.profile)
+		require.NoError(err)
+
+		result, err := CyclomaticComplexity{Profile: profile}.Analyze(context.Background(), fixtureUAST(t, c.file))
+		require.NoError(err)
+
+		var complexities []int
+		for _, f := range result.(*CyclomaticResult).Functions {
+			complexities = append(complexities, f.Complexity)
+		}
+		require.Equal(c.expect, complexities, "%s with %s", c.file, c.profile)
+	}
+
+	_, err := CyclomaticProfileByName("foo")
+	require.True(ErrUnknownCyclomatic.Is(err))
+	require.Equal([]string{"default", "gocyclo", "mccabe-strict", "pmd", "sonar"}, CyclomaticProfileNames())
+}
+
+func TestCyclomaticShortCircuit(t *testing.T) {
+	require := require.New(t)
+
+	for _, name := range CyclomaticProfileNames() {
+		profile, err := CyclomaticProfileByName(name)
+		require.NoError(err)
+		for _, shape := range booleanShapes() {
+			require.Equal(shape.conditions+1, profile.complexity(shape.n), "%s with %s", shape.name, name)
+		}
+	}
+
+	profile, err := ReadCyclomaticProfile(strings.NewReader(`{"name": "booleans", "short_circuit": true}`))
+	require.NoError(err)
+	require.Equal(&CyclomaticProfile{Name: "booleans", ShortCircuit: true}, profile)
+	for _, shape := range booleanShapes() {
+		require.Equal(shape.conditions+1, profile.complexity(shape.n), shape.name)
+	}
+}
+
+func TestReadCyclomaticProfile(t *testing.T) {
+	require := require.New(t)
+
+	rules := `{
+		"name": "ifs",
+		"rules": [
+			{"roles": ["Statement", "If"], "not": ["Then", "Else"]},
+			{"types": ["ConditionalExpression"]}
+		]
+	}`
+	profile, err := ReadCyclomaticProfile(strings.NewReader(rules))
+	require.NoError(err)
+	require.Equal(&CyclomaticProfile{Name: "ifs", Rules: []CyclomaticRule{
+		{Roles: []uast.Role{uast.Statement, uast.If}, NotRoles: []uast.Role{uast.Then, uast.Else}},
+		{Types: []string{"ConditionalExpression"}},
+	}}, profile)
+
+	// the rules are written back with the role names
+	data, err := json.Marshal(profile)
+	require.NoError(err)
+	again, err := ReadCyclomaticProfile(bytes.NewReader(data))
+	require.NoError(err)
+	require.Equal(profile, again)
+
+	result, err := CyclomaticComplexity{Profile: profile}.Analyze(context.Background(), fixtureUAST(t, "fixtures/npath/ternary.java.json"))
+	require.NoError(err)
+	require.Equal(6, result.(*CyclomaticResult).Complexity)
+
+	for _, rules := range []string{
+		`{"rules": [{"roles": ["Statement", "Foo"]}]}`,
+		`{"rules": []}`,
+		`{"rules": [{"not": ["Then"]}]}`,
+		`{"rules": `,
+	} {
+		_, err := ReadCyclomaticProfile(strings.NewReader(rules))
+		require.True(ErrInvalidRules.Is(err), rules)
+	}
+}
@@ -1,10 +1,26 @@
 package tools
 
-import "gopkg.in/bblfsh/sdk.v1/uast"
+import (
+	"context"
+
+	"gopkg.in/bblfsh/sdk.v1/uast"
+)
+
+const dummyMessage = "It works! You can now proceed with another tool :)"
 
 type Dummy struct{}
 
+// DummyResult is the result of the Dummy tool.
+type DummyResult struct {
+	Message string `json:"message"`
+}
+
 func (d Dummy) Exec(*uast.Node) error {
-	println("It works! You can now proceed with another tool :)")
+	println(dummyMessage)
 	return nil
 }
+
+// Analyze returns a *DummyResult.
+func (d Dummy) Analyze(context.Context, *uast.Node) (Result, error) {
+	return &DummyResult{Message: dummyMessage}, nil
+}
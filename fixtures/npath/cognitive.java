@@ -0,0 +1,47 @@
+class Code {
+	int sumOfPrimes(int max) {
+		int total = 0;
+		out:
+		for (int i = 1; i <= max; ++i) {
+			for (int j = 2; j < i; ++j) {
+				if (i % j == 0) {
+					continue out;
+				}
+			}
+			total += i;
+		}
+		return total;
+	}
+
+	String words(int number) {
+		switch (number) {
+			case 1:
+				return "one";
+			case 2:
+				return "a couple";
+			default:
+				return "lots";
+		}
+	}
+
+	int fact(int n) {
+		if (n <= 1 && n >= 0 || n == -1) {
+			return 1;
+		} else if (n < 0) {
+			return -1;
+		} else {
+			return n * fact(n - 1);
+		}
+	}
+
+	String sign(int n) {
+		try {
+			return n > 0 ? "positive" : "other";
+		} catch (RuntimeException e) {
+			while (n < 0) {
+				n = n > -10 ? n + 1 : 0;
+			}
+		}
+		return "";
+	}
+}
@@ -0,0 +1,49 @@
+class Code {
+	int find(int[] numbers, int target) {
+		int found = -1;
+		outer:
+		for (int i = 0; i < numbers.length; i++) {
+			if (numbers[i] < 0) {
+				continue;
+			}
+			if (numbers[i] == target) {
+				found = i;
+				break outer;
+			}
+		}
+		return found;
+	}
+
+	int days(int month, boolean leap) {
+		int days = 0;
+		switch (month) {
+			case 2:
+				days = leap ? 29 : 28;
+				break;
+			case 4:
+			case 6:
+			case 9:
+			case 11:
+				days = 30;
+				break;
+			default:
+				if (month > 12) {
+					return -1;
+				}
+				days = 31;
+		}
+		return days;
+	}
+
+	int countdown(int n) {
+		int steps = 0;
+		while (true) {
+			if (n <= 0) {
+				break;
+			}
+			n--;
+			steps++;
+		}
+		return steps;
+	}
+}
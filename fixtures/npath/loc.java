@@ -0,0 +1,17 @@
+/*
+ * Counts the lines.
+ */
+class Code {
+
+	// the sum of the numbers
+	int sum(int[] numbers) {
+		int total = 0; // the result
+
+		for (int n : numbers) {
+			total += n;
+		}
+		return total;
+	}
+
+	int zero() { return 0; }
+}
@@ -0,0 +1,17 @@
+class Code {
+	int max(int a, int b) {
+		return a > b ? a : b;
+	}
+
+	void sign(int a) {
+		String s = a > 0 ? "positive" : a < 0 ? "negative" : "zero";
+		System.out.println(s);
+	}
+
+	int clamp(int a, boolean strict) {
+		if (a < 0) {
+			return 0;
+		}
+		return a > 10 && strict ? 10 : a;
+	}
+}
@@ -0,0 +1,10 @@
+function max(a, b) {
+    return a > b ? a : b;
+}
+
+function label(n) {
+    var s = n > 0 ? "positive" : n < 0 ? "negative" : "zero";
+    return s;
+}
+
+const pick = (flag) => flag ? 1 : 2;
@@ -0,0 +1,13 @@
+def maximum(a, b):
+    return a if a > b else b
+
+
+def sign(n):
+    s = "positive" if n > 0 else "negative" if n < 0 else "zero"
+    return s
+
+
+def parity(n):
+    if n % 2 == 0 and n > 0:
+        return "even"
+    return "odd" if n else "zero"
@@ -0,0 +1,35 @@
+package shapes;
+
+public class Circle extends Shape {
+	private double radius;
+	private Color color;
+
+	public Circle(double radius) {
+		super("circle", 0);
+		this.radius = radius;
+	}
+
+	public double area() {
+		return Math.PI * radius * radius;
+	}
+
+	public String describe() {
+		if (color == null) {
+			return getName();
+		}
+		return color.name() + " " + getName();
+	}
+}
+
+class Ring extends Circle {
+	private double inner;
+
+	Ring(double radius, double inner) {
+		super(radius);
+		this.inner = inner;
+	}
+
+	public double area() {
+		return super.area() - Math.PI * inner * inner;
+	}
+}
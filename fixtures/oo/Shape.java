@@ -0,0 +1,21 @@
+package shapes;
+
+public abstract class Shape {
+	private String name;
+	private int sides;
+
+	public Shape(String name, int sides) {
+		this.name = name;
+		this.sides = sides;
+	}
+
+	public String getName() {
+		return name;
+	}
+
+	public boolean isPolygon() {
+		return sides > 0;
+	}
+
+	public abstract double area();
+}
@@ -0,0 +1,316 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/bblfsh/sdk.v1/uast"
+)
+
+// noName is the name given to the functions whose name is unknown.
+const noName = "NoName"
+
+// function is a function or method found in a UAST.
+type function struct {
+	// name is the qualified name of the function, made of the names of the
+	// enclosing namespaces and types, and the types of its arguments.
+	name string
+	// decl is the declaration of the function, it's nil when the analyzed
+	// node is itself a function body.
+	decl *uast.Node
+	body *uast.Node
+}
+
+// span returns the span of the whole function declaration.
+func (f *function) span() Span {
+	if f.decl != nil {
+		return NodeSpan(f.decl)
+	}
+	return NodeSpan(f.body)
+}
+
+// functions returns the functions with a body declared under n, in
+// the order they appear. If n is a function body, it's returned as the
+// only function.
+//
+// Nested functions, anonymous functions and lambdas, even when passed as
+// arguments, are functions on their own. They are named after the function
+// declaring them, like "outer$inner" or "outer$lambda@L42" for the ones
+// without a name.
+func functions(n *uast.Node) []*function {
+	if containsRoles(n, []uast.Role{uast.Function, uast.Body}, nil) {
+		return []*function{{name: unknownName(n), body: n}}
+	}
+
+	var funcs []*function
+	collectFunctions(n, scope{}, &funcs)
+	return funcs
+}
+
+// lambdaTypes are the internal types of anonymous functions for the drivers
+// that don't annotate them with the Anonymous role.
+var lambdaTypes = map[string]bool{
+	"ArrowFunctionExpression": true, // javascript
+	"FuncLit":                 true, // go
+	"FunctionExpression":      true, // javascript
+	"Lambda":                  true, // python
+	"LambdaExpression":        true, // java, csharp
+}
+
+func isLambda(n *uast.Node) bool {
+	return containsRoles(n, []uast.Role{uast.Function, uast.Anonymous}, nil) || lambdaTypes[n.InternalType]
+}
+
+// isFunction reports whether n is the declaration of a function, including
+// anonymous functions and lambdas.
+func isFunction(n *uast.Node) bool {
+	return functionBody(n) != nil
+}
+
+// functionBody returns the body of a function declaration or nil if n is not
+// a function declaration. Function arguments, which are also annotated as
+// function declarations, don't have a body. The body of the lambdas made of
+// just an expression is a node with all their children but the arguments.
+func functionBody(n *uast.Node) *uast.Node {
+	if !isLambda(n) && !containsRoles(n, []uast.Role{uast.Function, uast.Declaration}, nil) {
+		return nil
+	}
+
+	childFuncs := childrenOfRoles(n, []uast.Role{uast.Function, uast.Body}, nil)
+	if len(childFuncs) > 0 {
+		return childFuncs[0]
+	}
+	if !isLambda(n) {
+		return nil
+	}
+
+	body := &uast.Node{InternalType: n.InternalType, Roles: []uast.Role{uast.Function, uast.Body}}
+	for _, child := range n.Children {
+		if !containsRoles(child, []uast.Role{uast.Argument}, nil) && !containsRoles(child, []uast.Role{uast.Function, uast.Name}, nil) {
+			body.Children = append(body.Children, child)
+		}
+	}
+	return body
+}
+
+// scope is the qualified name of the namespace, type or function enclosing
+// a declaration.
+type scope struct {
+	name     string
+	function bool
+}
+
+// qualify returns the qualified name of a declaration in the scope.
+func (s scope) qualify(name string) string {
+	switch {
+	case name == "":
+		return s.name
+	case s.name == "":
+		return name
+	case s.function:
+		return s.name + "$" + name
+	default:
+		return s.name + "." + name
+	}
+}
+
+// collectFunctions finds the function declarations under n in the same way
+// as deepChildrenOfRoles, keeping track of the names of the namespaces,
+// types and functions enclosing them.
+func collectFunctions(n *uast.Node, s scope, funcs *[]*function) {
+	walkDeclarations(n, s, func(decl *uast.Node, name string) {
+		if isFunction(decl) {
+			*funcs = append(*funcs, &function{name: name, decl: decl, body: functionBody(decl)})
+		}
+	})
+}
+
+// walkDeclarations calls visit with every function and type declaration
+// under n, in the order they appear, and their qualified names.
+func walkDeclarations(n *uast.Node, s scope, visit func(decl *uast.Node, name string)) {
+	for _, child := range n.Children {
+		childScope := s
+		switch {
+		case isFunction(child):
+			name := functionName(child)
+			if name == "" && isLambda(child) {
+				name = lambdaName(child)
+			} else if name == "" {
+				name = unknownName(child)
+			}
+			name = s.qualify(name + argumentTypes(child))
+			visit(child, name)
+			childScope = scope{name: name, function: true}
+		case isTypeDeclaration(child):
+			name := s.qualify(declarationName(child))
+			visit(child, name)
+			childScope = scope{name: name}
+		case isNamespaceDeclaration(child):
+			name := declarationName(child)
+			if containsDeclarations(child) {
+				childScope = scope{name: s.qualify(name)}
+			} else {
+				// like a Java package, it names all the following declarations
+				s = scope{name: s.qualify(name), function: s.function}
+			}
+		}
+		walkDeclarations(child, childScope, visit)
+	}
+}
+
+// isTypeDeclaration reports whether n declares a type. Some drivers give
+// the Type role to the private members, like the Java "private" visibility,
+// so the functions and variables are not types.
+func isTypeDeclaration(n *uast.Node) bool {
+	return containsRoles(n, []uast.Role{uast.Type, uast.Declaration}, []uast.Role{uast.Function, uast.Variable})
+}
+
+func isNamespaceDeclaration(n *uast.Node) bool {
+	notRoles := []uast.Role{uast.Type, uast.Function, uast.Visibility}
+	return containsRoles(n, []uast.Role{uast.Package, uast.Declaration}, notRoles) ||
+		containsRoles(n, []uast.Role{uast.Module, uast.Declaration}, notRoles)
+}
+
+func containsDeclarations(n *uast.Node) bool {
+	return deepCountChildrenOfRoles(n, []uast.Role{uast.Function, uast.Declaration}, []uast.Role{uast.Argument}) > 0 ||
+		deepCountChildrenOfRoles(n, []uast.Role{uast.Type, uast.Declaration}, nil) > 0
+}
+
+// functionName returns the name of a function declaration, or an empty
+// string if it has none.
+func functionName(funcDec *uast.Node) string {
+	if containsRoles(funcDec, []uast.Role{uast.Function, uast.Name}, nil) && funcDec.Token != "" {
+		return funcDec.Token
+	}
+	childNames := childrenOfRoles(funcDec, []uast.Role{uast.Function, uast.Name}, nil)
+	if len(childNames) > 0 {
+		return childNames[0].Token
+	}
+	return ""
+}
+
+// unknownName is the name of a function without a name, including its line
+// when known to tell them apart.
+func unknownName(n *uast.Node) string {
+	return nameAtLine(noName, n)
+}
+
+// lambdaName is the name of an anonymous function.
+func lambdaName(n *uast.Node) string {
+	return nameAtLine("lambda", n)
+}
+
+func nameAtLine(name string, n *uast.Node) string {
+	if s := NodeSpan(n); s.StartLine > 0 {
+		return fmt.Sprintf("%s@L%d", name, s.StartLine)
+	}
+	return name
+}
+
+// declarationName returns the name of a type or namespace declaration: the
+// first child with the Name or Identifier role.
+func declarationName(n *uast.Node) string {
+	for _, roles := range [][]uast.Role{{uast.Name}, {uast.Identifier}} {
+		for _, child := range childrenOfRoles(n, roles, nil) {
+			if name := joinedTokens(child); name != "" {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// argumentTypes returns the types of the arguments of a function
+// declaration, like "(int,String[])". The types that are unknown are
+// written as "?", so the overloads with the same number of arguments are
+// still told apart by the known ones. It's empty if the function has no
+// arguments or none of them has a type, as in the languages without them,
+// which don't have overloads either.
+func argumentTypes(funcDec *uast.Node) string {
+	args := childrenOfRoles(funcDec, []uast.Role{uast.Argument}, nil)
+
+	var types []string
+	var known bool
+	for _, arg := range args {
+		t := "?"
+		if argTypes := childrenOfRoles(arg, []uast.Role{uast.Type}, nil); len(argTypes) > 0 {
+			if name := typeName(argTypes[0]); name != "" {
+				t, known = name, true
+			}
+		}
+		if arg.Properties["varargs"] == "true" {
+			t += "..."
+		}
+		types = append(types, t)
+	}
+	if !known {
+		return ""
+	}
+	return "(" + strings.Join(types, ",") + ")"
+}
+
+// typeName returns the name of a type as written in the source, like
+// "int[][]" or "Map<String,List<T>>", for the array and generic types
+// whose nodes have no token, or the joined tokens of any other type.
+func typeName(n *uast.Node) string {
+	if n.Token != "" {
+		return n.Token
+	}
+
+	switch n.InternalType {
+	case "ArrayType":
+		// the element type and a Dimension node per pair of brackets, or
+		// the component type with a pair of brackets in the older ASTs
+		var element string
+		var dimensions int
+		for _, child := range n.Children {
+			if child.InternalType == "Dimension" {
+				dimensions++
+			} else if element == "" {
+				element = typeName(child)
+			}
+		}
+		if element == "" {
+			return ""
+		}
+		if dimensions == 0 {
+			dimensions = 1
+		}
+		return element + strings.Repeat("[]", dimensions)
+	case "ParameterizedType":
+		// the generic type followed by its type arguments
+		if len(n.Children) == 0 {
+			return ""
+		}
+		var arguments []string
+		for _, child := range n.Children[1:] {
+			arguments = append(arguments, typeName(child))
+		}
+		return typeName(n.Children[0]) + "<" + strings.Join(arguments, ",") + ">"
+	case "WildcardType":
+		if len(n.Children) == 0 {
+			return "?"
+		}
+		bound := " super "
+		if n.Properties["upperBound"] != "false" {
+			bound = " extends "
+		}
+		return "?" + bound + typeName(n.Children[0])
+	}
+	return joinedTokens(n)
+}
+
+// joinedTokens returns the token of the node or, if it has none, the tokens
+// of its nearest descendants joined by dots, as in qualified identifiers.
+func joinedTokens(n *uast.Node) string {
+	if n.Token != "" {
+		return n.Token
+	}
+	var tokens []string
+	for _, child := range n.Children {
+		if t := joinedTokens(child); t != "" {
+			tokens = append(tokens, t)
+		}
+	}
+	return strings.Join(tokens, ".")
+}
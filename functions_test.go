@@ -0,0 +1,73 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/bblfsh/sdk.v1/uast"
+)
+
+func TestFunctionNamesArgumentTypes(t *testing.T) {
+	require := require.New(t)
+
+	simple := func(token string) *uast.Node {
+		return &uast.Node{InternalType: "SimpleType", Roles: []uast.Role{uast.Type}, Token: token}
+	}
+	array := func(element *uast.Node, dimensions int) *uast.Node {
+		n := &uast.Node{InternalType: "ArrayType", Roles: []uast.Role{uast.Type}, Children: []*uast.Node{element}}
+		for i := 0; i < dimensions; i++ {
+			n.Children = append(n.Children, &uast.Node{InternalType: "Dimension"})
+		}
+		return n
+	}
+	generic := func(base *uast.Node, arguments ...*uast.Node) *uast.Node {
+		return &uast.Node{InternalType: "ParameterizedType", Roles: []uast.Role{uast.Type},
+			Children: append([]*uast.Node{base}, arguments...)}
+	}
+	// an argument, without type if it's nil
+	argument := func(typ *uast.Node) *uast.Node {
+		n := &uast.Node{InternalType: "SingleVariableDeclaration", Roles: []uast.Role{uast.Function, uast.Declaration, uast.Argument}}
+		if typ != nil {
+			n.Children = append(n.Children, typ)
+		}
+		return n
+	}
+	f := func(args ...*uast.Node) *uast.Node {
+		return &uast.Node{InternalType: "MethodDeclaration", Roles: []uast.Role{uast.Function, uast.Declaration},
+			Children: append(append([]*uast.Node{
+				{InternalType: "SimpleName", Roles: []uast.Role{uast.Function, uast.Name}, Token: "f"},
+			}, args...), &uast.Node{InternalType: "Block", Roles: []uast.Role{uast.Function, uast.Body}})}
+	}
+	varargs := argument(simple("String"))
+	varargs.Properties = map[string]string{"varargs": "true"}
+
+	class := &uast.Node{InternalType: "TypeDeclaration", Roles: []uast.Role{uast.Type, uast.Declaration}, Children: []*uast.Node{
+		{InternalType: "SimpleName", Roles: []uast.Role{uast.Identifier}, Token: "Code"},
+		f(argument(simple("int"))),
+		f(argument(array(simple("int"), 0))),
+		f(argument(array(simple("int"), 2))),
+		f(argument(generic(simple("Map"), simple("String"), generic(simple("List"), array(simple("int"), 1))))),
+		f(argument(generic(simple("List"), &uast.Node{InternalType: "WildcardType", Roles: []uast.Role{uast.Type}}))),
+		f(argument(nil), argument(simple("int"))),
+		f(argument(simple("int")), argument(nil)),
+		f(varargs),
+		f(argument(nil), argument(nil)),
+	}}
+	n := &uast.Node{InternalType: "CompilationUnit", Children: []*uast.Node{class}}
+
+	var names []string
+	for _, fn := range functions(n) {
+		names = append(names, fn.name)
+	}
+	require.Equal([]string{
+		"Code.f(int)",
+		"Code.f(int[])",
+		"Code.f(int[][])",
+		"Code.f(Map<String,List<int[]>>)",
+		"Code.f(List<?>)",
+		"Code.f(?,int)",
+		"Code.f(int,?)",
+		"Code.f(String...)",
+		"Code.f",
+	}, names)
+}
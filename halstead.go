@@ -0,0 +1,190 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"gopkg.in/bblfsh/sdk.v1/uast"
+)
+
+type Halstead struct{}
+
+// HalsteadMetrics are the Halstead complexity measures of a piece of code,
+// computed from the number of its operators and operands.
+type HalsteadMetrics struct {
+	DistinctOperators int `json:"distinct_operators"`
+	DistinctOperands  int `json:"distinct_operands"`
+	TotalOperators    int `json:"total_operators"`
+	TotalOperands     int `json:"total_operands"`
+
+	Vocabulary int     `json:"vocabulary"`
+	Length     int     `json:"length"`
+	Volume     float64 `json:"volume"`
+	Difficulty float64 `json:"difficulty"`
+	Effort     float64 `json:"effort"`
+	// Bugs is the estimated number of delivered bugs.
+	Bugs float64 `json:"bugs"`
+	// Time is the estimated time to program it, in seconds.
+	Time float64 `json:"time"`
+}
+
+func (hm HalsteadMetrics) String() string {
+	return fmt.Sprintf("Operators:%d/%d, Operands:%d/%d, Vocabulary:%d, Length:%d, Volume:%.2f, Difficulty:%.2f, Effort:%.2f, Bugs:%.3f, Time:%.2fs",
+		hm.DistinctOperators, hm.TotalOperators, hm.DistinctOperands, hm.TotalOperands,
+		hm.Vocabulary, hm.Length, hm.Volume, hm.Difficulty, hm.Effort, hm.Bugs, hm.Time)
+}
+
+// HalsteadData is the Halstead metrics of a function.
+type HalsteadData struct {
+	Name string `json:"name"`
+	HalsteadMetrics
+	Span
+}
+
+func (hd *HalsteadData) String() string {
+	return fmt.Sprintf("FuncName:%s, %s, Position:%s\n", hd.Name, hd.HalsteadMetrics, hd.Span)
+}
+
+// HalsteadResult is the result of the Halstead tool.
+type HalsteadResult struct {
+	// File has the metrics of the whole analyzed node.
+	File      HalsteadMetrics `json:"file"`
+	Functions []*HalsteadData `json:"functions"`
+}
+
+// Analyze returns a *HalsteadResult with the Halstead metrics of the node
+// and of every function in it.
+func (h Halstead) Analyze(ctx context.Context, n *uast.Node) (Result, error) {
+	result := &HalsteadResult{File: HalsteadOf(n)}
+	for _, function := range functions(n) {
+		decl := function.decl
+		if decl == nil {
+			decl = function.body
+		}
+		result.Functions = append(result.Functions, &HalsteadData{
+			Name:            function.name,
+			HalsteadMetrics: HalsteadOf(decl),
+			Span:            function.span(),
+		})
+	}
+	return result, nil
+}
+
+// HalsteadOf computes the Halstead metrics of a *uast.Node. See:
+// https://en.wikipedia.org/wiki/Halstead_complexity_measures
+//
+// The nodes are classified by their roles, in the same order as Tokens:
+// * the operators are the nodes with the Operator role, named after their
+// token or their "operator" property, the control flow statements, named
+// after their keyword, and the calls, all named "()".
+// * the operands are the identifiers and literals without children, named
+// after their token or value.
+//
+// Names, types and modifiers that are not identifiers, like the keywords of
+// a declaration, are not counted.
+func HalsteadOf(n *uast.Node) HalsteadMetrics {
+	operators := make(map[string]int)
+	operands := make(map[string]int)
+
+	var m HalsteadMetrics
+	iter := uast.NewOrderPathIter(uast.NewPath(n))
+	for {
+		p := iter.Next()
+		if p.IsEmpty() {
+			break
+		}
+
+		n := p.Node()
+		if operator, ok := halsteadOperator(n); ok {
+			operators[operator]++
+			m.TotalOperators++
+		} else if operand, ok := halsteadOperand(n); ok {
+			operands[operand]++
+			m.TotalOperands++
+		}
+	}
+	m.DistinctOperators = len(operators)
+	m.DistinctOperands = len(operands)
+
+	m.Vocabulary = m.DistinctOperators + m.DistinctOperands
+	m.Length = m.TotalOperators + m.TotalOperands
+	if m.Vocabulary > 0 {
+		m.Volume = float64(m.Length) * math.Log2(float64(m.Vocabulary))
+	}
+	if m.DistinctOperands > 0 {
+		m.Difficulty = float64(m.DistinctOperators) / 2 * float64(m.TotalOperands) / float64(m.DistinctOperands)
+	}
+	m.Effort = m.Difficulty * m.Volume
+	m.Bugs = m.Volume / 3000
+	m.Time = m.Effort / 18
+	return m
+}
+
+// halsteadKeywords are the statements counted as operators, by the keyword
+// most languages use for them.
+var halsteadKeywords = []struct {
+	rule    CyclomaticRule
+	keyword string
+}{
+	{ifRule, "if"},
+	{forRule, "for"},
+	{whileRule, "while"},
+	{doWhileRule, "do"},
+	{CyclomaticRule{Roles: []uast.Role{uast.Statement, uast.Switch}, NotRoles: []uast.Role{uast.Case, uast.Default, uast.Body}}, "switch"},
+	{caseRule, "case"},
+	{CyclomaticRule{Roles: []uast.Role{uast.Statement, uast.Switch, uast.Default}, NotRoles: []uast.Role{uast.Body}}, "default"},
+	{CyclomaticRule{Roles: []uast.Role{uast.Statement, uast.Try}, ChildRoles: []uast.Role{uast.Try, uast.Body}}, "try"},
+	{catchRule, "catch"},
+	{CyclomaticRule{Roles: []uast.Role{uast.Statement, uast.Return}}, "return"},
+	{CyclomaticRule{Roles: []uast.Role{uast.Statement, uast.Break}}, "break"},
+	{CyclomaticRule{Roles: []uast.Role{uast.Statement, uast.Continue}}, "continue"},
+	{CyclomaticRule{Roles: []uast.Role{uast.Statement, uast.Goto}}, "goto"},
+	{CyclomaticRule{Roles: []uast.Role{uast.Statement, uast.Throw}}, "throw"},
+}
+
+// halsteadCall matches the calls, which have their callee as a child.
+var halsteadCall = CyclomaticRule{Roles: []uast.Role{uast.Call}, ChildRoles: []uast.Role{uast.Call, uast.Callee}}
+
+// halsteadOperator returns the name of the operator n, if it's one.
+func halsteadOperator(n *uast.Node) (string, bool) {
+	if containsRoles(n, []uast.Role{uast.Operator}, nil) {
+		switch {
+		case n.Token != "":
+			return n.Token, true
+		case n.Properties["operator"] != "":
+			return n.Properties["operator"], true
+		default:
+			return n.InternalType, true
+		}
+	}
+	for _, k := range halsteadKeywords {
+		if k.rule.matches(n) {
+			return k.keyword, true
+		}
+	}
+	if halsteadCall.matches(n) {
+		return "()", true
+	}
+	return "", false
+}
+
+// halsteadValueProperties are the properties holding the value of the
+// literals without a token.
+var halsteadValueProperties = []string{"token", "booleanValue", "value"}
+
+// halsteadOperand returns the name of the operand n, if it's one.
+func halsteadOperand(n *uast.Node) (string, bool) {
+	if len(n.Children) > 0 || (!containsRoles(n, []uast.Role{uast.Identifier}, nil) && !containsRoles(n, []uast.Role{uast.Literal}, nil)) {
+		return "", false
+	}
+	if n.Token != "" {
+		return n.Token, true
+	}
+	for _, p := range halsteadValueProperties {
+		if v := n.Properties[p]; v != "" {
+			return v, true
+		}
+	}
+	return n.InternalType, true
+}
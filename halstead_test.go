@@ -0,0 +1,70 @@
+package tools
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/bblfsh/sdk.v1/uast"
+)
+
+func TestHalsteadFixture(t *testing.T) {
+	require := require.New(t)
+
+	result, err := Halstead{}.Analyze(context.Background(), fixtureUAST(t, "fixtures/npath/ifelse.java.json"))
+	require.NoError(err)
+	r := result.(*HalsteadResult)
+
+	// operators: if, () x2
+	// operands: code, true x2, System x2, out x2, println x2, false
+	require.Len(r.Functions, 1)
+	f := r.Functions[0]
+	require.Equal("Code.code", f.Name)
+	require.Equal(2, f.DistinctOperators)
+	require.Equal(3, f.TotalOperators)
+	require.Equal(6, f.DistinctOperands)
+	require.Equal(10, f.TotalOperands)
+	require.Equal(8, f.Vocabulary)
+	require.Equal(13, f.Length)
+	require.InDelta(39, f.Volume, 1e-9)
+	require.InDelta(5.0/3, f.Difficulty, 1e-9)
+	require.InDelta(65, f.Effort, 1e-9)
+	require.InDelta(0.013, f.Bugs, 1e-9)
+	require.InDelta(65.0/18, f.Time, 1e-9)
+
+	// the file also has the name of the class
+	require.Equal(2, r.File.DistinctOperators)
+	require.Equal(7, r.File.DistinctOperands)
+	require.Equal(11, r.File.TotalOperands)
+}
+
+func TestHalsteadOf(t *testing.T) {
+	require := require.New(t)
+
+	id := func(token string) *uast.Node {
+		return &uast.Node{InternalType: "Name", Roles: []uast.Role{uast.Expression, uast.Identifier}, Token: token}
+	}
+	// a = a + 1; return a
+	n := &uast.Node{InternalType: "body", Children: []*uast.Node{
+		{InternalType: "Assign", Roles: []uast.Role{uast.Expression, uast.Assignment, uast.Operator}, Properties: map[string]string{"operator": "="}, Children: []*uast.Node{
+			id("a"),
+			{InternalType: "BinOp", Roles: []uast.Role{uast.Expression, uast.Binary}, Children: []*uast.Node{
+				id("a"),
+				{InternalType: "Add", Roles: []uast.Role{uast.Operator, uast.Arithmetic, uast.Add}, Token: "+"},
+				{InternalType: "Num", Roles: []uast.Role{uast.Expression, uast.Literal, uast.Number}, Properties: map[string]string{"token": "1"}},
+			}},
+		}},
+		{InternalType: "Return", Roles: []uast.Role{uast.Statement, uast.Return}, Children: []*uast.Node{id("a")}},
+	}}
+
+	m := HalsteadOf(n)
+	require.Equal(3, m.DistinctOperators)
+	require.Equal(3, m.TotalOperators)
+	require.Equal(2, m.DistinctOperands)
+	require.Equal(4, m.TotalOperands)
+	require.Equal(7, m.Length)
+	require.InDelta(7*2.321928094887362, m.Volume, 1e-9)
+	require.InDelta(3.0, m.Difficulty, 1e-9)
+
+	require.Equal(HalsteadMetrics{}, HalsteadOf(&uast.Node{}))
+}
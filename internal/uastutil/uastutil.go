@@ -0,0 +1,128 @@
+// Package uastutil has the helpers on UAST nodes shared by the tools and the
+// packages they use, like cfg.
+package uastutil
+
+import (
+	"sort"
+
+	"gopkg.in/bblfsh/sdk.v1/uast"
+)
+
+// HasRoles reports whether n has all the roles.
+func HasRoles(n *uast.Node, roles ...uast.Role) bool {
+	for _, r := range roles {
+		if !HasAnyRole(n, r) {
+			return false
+		}
+	}
+	return true
+}
+
+// HasAnyRole reports whether n has any of the roles.
+func HasAnyRole(n *uast.Node, roles ...uast.Role) bool {
+	for _, role := range n.Roles {
+		for _, r := range roles {
+			if role == r {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ValidPosition reports whether p is a known position.
+func ValidPosition(p *uast.Position) bool {
+	return p != nil && p.Line > 0
+}
+
+// Before reports whether the position a comes before b. They are compared
+// by their offsets, or by their lines and columns when any offset is zero,
+// since some drivers only fill the lines and columns.
+func Before(a, b *uast.Position) bool {
+	if a.Offset == 0 || b.Offset == 0 {
+		return a.Line < b.Line || (a.Line == b.Line && a.Col < b.Col)
+	}
+	return a.Offset < b.Offset
+}
+
+// StartPosition returns the start position of the node or the lowest one
+// of its nearest descendants with a start position, nil if none has it.
+func StartPosition(n *uast.Node) *uast.Position {
+	if ValidPosition(n.StartPosition) {
+		return n.StartPosition
+	}
+	var min *uast.Position
+	for _, child := range n.Children {
+		if p := StartPosition(child); p != nil && (min == nil || Before(p, min)) {
+			min = p
+		}
+	}
+	return min
+}
+
+// EndPosition returns the end position of the node or the highest one of
+// its nearest descendants with an end position, nil if none has it.
+func EndPosition(n *uast.Node) *uast.Position {
+	if ValidPosition(n.EndPosition) {
+		return n.EndPosition
+	}
+	var max *uast.Position
+	for _, child := range n.Children {
+		if p := EndPosition(child); p != nil && (max == nil || Before(max, p)) {
+			max = p
+		}
+	}
+	return max
+}
+
+// IsSwitchLabel reports whether n is a case or default label of a switch.
+// The statements following a label are not labels even if they are annotated
+// as part of the case.
+func IsSwitchLabel(n *uast.Node) bool {
+	return (HasRoles(n, uast.Switch, uast.Case) || HasRoles(n, uast.Switch, uast.Default)) &&
+		!HasAnyRole(n, uast.Body, uast.Condition)
+}
+
+// SwitchChildren returns the switch expression, the labels and the
+// statements of a switch, in the order they appear in the code when it can
+// be told by their positions, since some drivers move the nodes around. When
+// the labels are in a block, its children come after the switch expression.
+func SwitchChildren(n *uast.Node) []*uast.Node {
+	children := n.Children
+	if !hasLabels(n) {
+		for i, child := range n.Children {
+			if hasLabels(child) {
+				children = append(append([]*uast.Node{}, n.Children[:i]...), child.Children...)
+				break
+			}
+		}
+	}
+
+	starts := make([]*uast.Position, len(children))
+	for i, child := range children {
+		if starts[i] = StartPosition(child); starts[i] == nil {
+			return children
+		}
+	}
+	indexes := make([]int, len(children))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	sort.SliceStable(indexes, func(i, j int) bool {
+		return Before(starts[indexes[i]], starts[indexes[j]])
+	})
+	sorted := make([]*uast.Node, len(children))
+	for i, index := range indexes {
+		sorted[i] = children[index]
+	}
+	return sorted
+}
+
+func hasLabels(n *uast.Node) bool {
+	for _, child := range n.Children {
+		if IsSwitchLabel(child) {
+			return true
+		}
+	}
+	return false
+}
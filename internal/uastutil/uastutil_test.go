@@ -0,0 +1,54 @@
+package uastutil
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/bblfsh/sdk.v1/uast"
+)
+
+func TestSwitchChildren(t *testing.T) {
+	require := require.New(t)
+
+	at := func(internalType string, offset uint32, roles ...uast.Role) *uast.Node {
+		n := &uast.Node{InternalType: internalType, Roles: roles}
+		if offset > 0 {
+			n.StartPosition = &uast.Position{Line: 1, Col: offset + 1, Offset: offset}
+		}
+		return n
+	}
+	types := func(nodes []*uast.Node) []string {
+		var types []string
+		for _, n := range nodes {
+			types = append(types, n.InternalType)
+		}
+		return types
+	}
+
+	// sorted by their positions
+	n := &uast.Node{Roles: []uast.Role{uast.Statement, uast.Switch}, Children: []*uast.Node{
+		at("expr", 1, uast.Expression, uast.Switch),
+		at("case", 2, uast.Statement, uast.Switch, uast.Case),
+		at("default", 5, uast.Statement, uast.Switch, uast.Default),
+		at("a", 3, uast.Statement, uast.Switch, uast.Case, uast.Body),
+		at("break", 4, uast.Statement, uast.Break),
+	}}
+	require.True(IsSwitchLabel(n.Children[1]))
+	require.True(IsSwitchLabel(n.Children[2]))
+	require.False(IsSwitchLabel(n.Children[3]))
+	require.Equal([]string{"expr", "case", "a", "break", "default"}, types(SwitchChildren(n)))
+
+	// left as they are if any has no position
+	n.Children[4] = at("break", 0, uast.Statement, uast.Break)
+	require.Equal([]string{"expr", "case", "default", "a", "break"}, types(SwitchChildren(n)))
+
+	// the labels in a block come after the expression
+	n = &uast.Node{Roles: []uast.Role{uast.Statement, uast.Switch}, Children: []*uast.Node{
+		at("expr", 0, uast.Expression, uast.Switch),
+		{InternalType: "block", Children: []*uast.Node{
+			at("case", 0, uast.Statement, uast.Switch, uast.Case),
+			at("a", 0, uast.Statement),
+		}},
+	}}
+	require.Equal([]string{"expr", "case", "a"}, types(SwitchChildren(n)))
+}
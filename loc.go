@@ -0,0 +1,203 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/bblfsh/tools/internal/uastutil"
+	"gopkg.in/bblfsh/sdk.v1/uast"
+)
+
+type LOC struct{}
+
+// LineCounts are the number of lines of a piece of code by their contents.
+// A line with code and a trailing comment is both a source and a comment
+// line, so the source, comment and blank lines may add up to more than the
+// physical lines.
+type LineCounts struct {
+	// Physical are all the lines, including the blank ones.
+	Physical int `json:"physical"`
+	// Source are the lines with code, anything but comments and blanks.
+	Source int `json:"source"`
+	// Logical are the statements, no matter how many lines they take.
+	Logical int `json:"logical"`
+	Comment int `json:"comment"`
+	Blank   int `json:"blank"`
+}
+
+func (lc LineCounts) String() string {
+	return fmt.Sprintf("Physical:%d, Source:%d, Logical:%d, Comment:%d, Blank:%d",
+		lc.Physical, lc.Source, lc.Logical, lc.Comment, lc.Blank)
+}
+
+// LOCData is the number of lines of a function.
+type LOCData struct {
+	Name string `json:"name"`
+	LineCounts
+	Span
+}
+
+func (ld *LOCData) String() string {
+	return fmt.Sprintf("FuncName:%s, %s, Position:%s\n", ld.Name, ld.LineCounts, ld.Span)
+}
+
+// LOCResult is the result of the LOC tool.
+type LOCResult struct {
+	// File has the lines of the whole analyzed node.
+	File      LineCounts `json:"file"`
+	Functions []*LOCData `json:"functions"`
+}
+
+// Analyze returns a *LOCResult with the lines of the node and of every
+// function in it, told apart only by the positions of the nodes: the lines
+// without any node are counted as blank.
+func (l LOC) Analyze(ctx context.Context, n *uast.Node) (Result, error) {
+	return l.AnalyzeSource(ctx, n, "")
+}
+
+// AnalyzeSource returns a *LOCResult with the lines of the node and of every
+// function in it. The source tells the blank lines apart from the ones with
+// code not represented in the UAST, like closing braces, and the code from
+// the comments on the same line.
+//
+// The comments are the nodes with the Comment role, the comment lines within
+// the span of a function are counted even if the driver puts them elsewhere
+// in the tree. The functions are found as in NPathComplexity, the lines of
+// the nested functions also count for the ones declaring them.
+func (l LOC) AnalyzeSource(ctx context.Context, n *uast.Node, source string) (Result, error) {
+	lines := classifyLines(n, source)
+
+	result := &LOCResult{File: lines.count(1, lines.last)}
+	result.File.Logical = countStatements(n)
+	for _, function := range functions(n) {
+		decl := function.decl
+		if decl == nil {
+			decl = function.body
+		}
+
+		span := function.span()
+		data := &LOCData{Name: function.name, Span: span}
+		if span.StartLine > 0 {
+			data.LineCounts = lines.count(span.StartLine, span.EndLine)
+		}
+		data.Logical = countStatements(function.body)
+		result.Functions = append(result.Functions, data)
+	}
+	return result, nil
+}
+
+// lineKinds tells which lines, numbered from 1, have code and comments.
+type lineKinds struct {
+	code    map[uint32]bool
+	comment map[uint32]bool
+	last    uint32
+}
+
+// classifyLines finds the lines with code and comments. Without source, the
+// code lines are the ones where any node but a comment starts or ends.
+func classifyLines(n *uast.Node, source string) *lineKinds {
+	comments := deepChildrenOfRoles(n, []uast.Role{uast.Comment}, nil)
+	if containsRoles(n, []uast.Role{uast.Comment}, nil) {
+		comments = append(comments, n)
+	}
+
+	l := &lineKinds{comment: make(map[uint32]bool)}
+	for _, comment := range comments {
+		s := NodeSpan(comment)
+		for line := s.StartLine; line <= s.EndLine && line > 0; line++ {
+			l.comment[line] = true
+		}
+	}
+
+	if source == "" {
+		l.code = codeLines(n)
+		l.last = NodeSpan(n).EndLine
+		for line := range l.comment {
+			if line > l.last {
+				l.last = line
+			}
+		}
+		return l
+	}
+
+	lines := strings.SplitAfter(source, "\n")
+	lineStarts := make([]int, len(lines))
+	for i := 1; i < len(lines); i++ {
+		lineStarts[i] = lineStarts[i-1] + len(lines[i-1])
+	}
+
+	// the bytes of the comments, which are not code
+	inComment := make([]bool, len(source))
+	for _, comment := range comments {
+		start, end := uastutil.StartPosition(comment), uastutil.EndPosition(comment)
+		if start == nil || end == nil {
+			continue
+		}
+		for i := sourceOffset(start, lineStarts); i < sourceOffset(end, lineStarts) && i < len(source); i++ {
+			inComment[i] = true
+		}
+	}
+
+	l.code = make(map[uint32]bool)
+	offset := 0
+	for i, text := range lines {
+		line := uint32(i + 1)
+		for j, c := range text {
+			if !strings.ContainsRune(" \t\r\n\f\v", c) && !inComment[offset+j] {
+				l.code[line] = true
+				break
+			}
+		}
+		if text != "" {
+			l.last = line
+		}
+		offset += len(text)
+	}
+	return l
+}
+
+// sourceOffset returns the byte offset of a position in the source, whose
+// lines start at the lineStarts offsets. It's computed from the line and
+// column when the offset is zero but the position is not at the start of
+// the source, since some drivers only fill the lines and columns.
+//
+// The offsets and columns are taken as bytes, so the drivers counting them
+// in other units, like the UTF-16 code units of the Java one, only give the
+// right offsets for the ASCII sources.
+func sourceOffset(p *uast.Position, lineStarts []int) int {
+	if p.Offset > 0 || p.Line == 0 || int(p.Line) > len(lineStarts) {
+		return int(p.Offset)
+	}
+	offset := lineStarts[p.Line-1]
+	if p.Col > 1 {
+		offset += int(p.Col) - 1
+	}
+	return offset
+}
+
+// count returns the lines between first and last, both included.
+func (l *lineKinds) count(first, last uint32) LineCounts {
+	var c LineCounts
+	for line := first; line <= last; line++ {
+		c.Physical++
+		switch {
+		case l.code[line] && l.comment[line]:
+			c.Source++
+			c.Comment++
+		case l.code[line]:
+			c.Source++
+		case l.comment[line]:
+			c.Comment++
+		default:
+			c.Blank++
+		}
+	}
+	return c
+}
+
+// countStatements returns the number of statements under n, but the blocks
+// grouping them.
+func countStatements(n *uast.Node) int {
+	return deepCountChildrenOfRoles(n, []uast.Role{uast.Statement}, []uast.Role{uast.Block})
+}
@@ -0,0 +1,76 @@
+package tools
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/bblfsh/sdk.v1/uast"
+)
+
+func TestLOCSource(t *testing.T) {
+	require := require.New(t)
+
+	source, err := ioutil.ReadFile("fixtures/npath/loc.java")
+	require.NoError(err)
+	// the fixture is hand-written, see fixtures/README.md
+	result, err := LOC{}.AnalyzeSource(context.Background(), fixtureUAST(t, "fixtures/npath/loc.java.json"), string(source))
+	require.NoError(err)
+	r := result.(*LOCResult)
+
+	// line 8 has code and a trailing comment, 12 and 14 just a closing brace
+	require.Equal(LineCounts{Physical: 17, Source: 10, Logical: 5, Comment: 5, Blank: 3}, r.File)
+	require.Len(r.Functions, 2)
+	require.Equal("Code.sum(int[])", r.Functions[0].Name)
+	require.Equal(LineCounts{Physical: 7, Source: 6, Logical: 4, Comment: 1, Blank: 1}, r.Functions[0].LineCounts)
+	require.Equal("Code.zero", r.Functions[1].Name)
+	require.Equal(LineCounts{Physical: 1, Source: 1, Logical: 1}, r.Functions[1].LineCounts)
+}
+
+func TestLOCSourceWithoutOffsets(t *testing.T) {
+	require := require.New(t)
+
+	n := fixtureUAST(t, "fixtures/npath/loc.java.json")
+	clearOffsets(n)
+
+	source, err := ioutil.ReadFile("fixtures/npath/loc.java")
+	require.NoError(err)
+	result, err := LOC{}.AnalyzeSource(context.Background(), n, string(source))
+	require.NoError(err)
+	r := result.(*LOCResult)
+
+	require.Equal(LineCounts{Physical: 17, Source: 10, Logical: 5, Comment: 5, Blank: 3}, r.File)
+	require.Equal(LineCounts{Physical: 7, Source: 6, Logical: 4, Comment: 1, Blank: 1}, r.Functions[0].LineCounts)
+}
+
+func TestLOCPositions(t *testing.T) {
+	require := require.New(t)
+
+	result, err := LOC{}.Analyze(context.Background(), fixtureUAST(t, "fixtures/npath/loc.java.json"))
+	require.NoError(err)
+	r := result.(*LOCResult)
+
+	// without the source, the lines of the closing braces look blank and
+	// the last one is not even counted
+	require.Equal(LineCounts{Physical: 16, Source: 7, Logical: 5, Comment: 5, Blank: 5}, r.File)
+	require.Equal(LineCounts{Physical: 7, Source: 5, Logical: 4, Comment: 1, Blank: 2}, r.Functions[0].LineCounts)
+}
+
+func TestLOCWithoutPositions(t *testing.T) {
+	require := require.New(t)
+
+	n := &uast.Node{InternalType: "module", Children: []*uast.Node{
+		{InternalType: "func", Roles: []uast.Role{uast.Function, uast.Declaration}, Children: []*uast.Node{
+			{InternalType: "body", Roles: []uast.Role{uast.Function, uast.Body}, Children: []*uast.Node{
+				{InternalType: "statement", Roles: []uast.Role{uast.Statement}},
+			}},
+		}},
+	}}
+
+	result, err := LOC{}.AnalyzeSource(context.Background(), n, "f()\n\n")
+	require.NoError(err)
+	r := result.(*LOCResult)
+	require.Equal(LineCounts{Physical: 2, Source: 1, Logical: 1, Blank: 1}, r.File)
+	require.Equal(LineCounts{Logical: 1}, r.Functions[0].LineCounts)
+}
@@ -0,0 +1,147 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/bblfsh/tools/internal/uastutil"
+	"gopkg.in/bblfsh/sdk.v1/uast"
+)
+
+// Maintainability computes the maintainability index of the functions, see:
+// https://docs.microsoft.com/en-us/visualstudio/code-quality/code-metrics-values
+//
+// The index combines the Halstead volume (V), the cyclomatic complexity (G)
+// and the lines of code (LOC) of the function:
+//
+//	MI = 171 - 5.2 ln(V) - 0.23 G - 16.2 ln(LOC)
+//
+// The lines of code are the lines where any node but a comment starts or
+// ends, as told by their positions, so blank lines and the ones with just a
+// closing brace are usually not counted. With Comments, the comment lines
+// within the span of the function are also counted, even if the driver puts
+// the comments elsewhere in the tree, and the index adds:
+//
+//	50 sin(sqrt(2.4 CM))
+//
+// where CM is the percentage of comment lines, converted to radians.
+//
+// The normalized index is max(0, MI * 100 / 171), capped at 100.
+type Maintainability struct {
+	// Comments adds the comment term to the index, which grows with the
+	// ratio of comment lines of the function.
+	Comments bool
+	// Profile has the rules of the cyclomatic complexity, the default
+	// profile is used if it's nil.
+	Profile *CyclomaticProfile
+}
+
+// MaintainabilityData is the maintainability index of a function, with the
+// metrics it's computed from.
+type MaintainabilityData struct {
+	Name string `json:"name"`
+	// Index is the original maintainability index, 171 at most and
+	// unbounded below.
+	Index float64 `json:"index"`
+	// Normalized is the index rescaled to 0-100, as in Visual Studio.
+	Normalized   float64 `json:"normalized"`
+	Volume       float64 `json:"volume"`
+	Cyclomatic   int     `json:"cyclomatic"`
+	Lines        int     `json:"lines"`
+	CommentLines int     `json:"comment_lines"`
+	Span
+}
+
+// MaintainabilityResult is the result of the Maintainability tool.
+type MaintainabilityResult struct {
+	Functions []*MaintainabilityData `json:"functions"`
+}
+
+func (md *MaintainabilityData) String() string {
+	return fmt.Sprintf("FuncName:%s, Index:%.2f, Normalized:%.2f, Volume:%.2f, Cyclomatic:%d, Lines:%d, CommentLines:%d, Position:%s\n",
+		md.Name, md.Index, md.Normalized, md.Volume, md.Cyclomatic, md.Lines, md.CommentLines, md.Span)
+}
+
+// Analyze returns a *MaintainabilityResult with the maintainability index of
+// every function in the node.
+func (m Maintainability) Analyze(ctx context.Context, n *uast.Node) (Result, error) {
+	profile := CyclomaticComplexity{Profile: m.Profile}.profile()
+
+	var comments []*uast.Node
+	if m.Comments {
+		comments = deepChildrenOfRoles(n, []uast.Role{uast.Comment}, nil)
+	}
+
+	result := &MaintainabilityResult{}
+	for _, function := range functions(n) {
+		decl := function.decl
+		if decl == nil {
+			decl = function.body
+		}
+
+		span := function.span()
+		data := &MaintainabilityData{
+			Name:       function.name,
+			Volume:     HalsteadOf(decl).Volume,
+			Cyclomatic: profile.complexity(function.body),
+			Lines:      len(codeLines(decl)),
+			Span:       span,
+		}
+		if m.Comments {
+			data.CommentLines = commentLines(comments, span)
+		}
+		data.Index = maintainabilityIndex(data.Volume, data.Cyclomatic, data.Lines, data.CommentLines, m.Comments)
+		data.Normalized = math.Min(100, math.Max(0, data.Index*100/171))
+		result.Functions = append(result.Functions, data)
+	}
+	return result, nil
+}
+
+func maintainabilityIndex(volume float64, cyclomatic, lines, comments int, withComments bool) float64 {
+	// empty functions have no volume, nor lines if their positions are unknown
+	index := 171 - 5.2*math.Log(math.Max(1, volume)) - 0.23*float64(cyclomatic) - 16.2*math.Log(math.Max(1, float64(lines)))
+	if withComments && lines+comments > 0 {
+		percent := 100 * float64(comments) / float64(lines+comments)
+		index += 50 * math.Sin(math.Sqrt(2.4*percent*math.Pi/180))
+	}
+	return index
+}
+
+// codeLines returns the lines where the nodes under n start or end, but
+// the comments.
+func codeLines(n *uast.Node) map[uint32]bool {
+	lines := make(map[uint32]bool)
+	collectCodeLines(n, lines)
+	return lines
+}
+
+func collectCodeLines(n *uast.Node, lines map[uint32]bool) {
+	if containsRoles(n, []uast.Role{uast.Comment}, nil) {
+		return
+	}
+	if uastutil.ValidPosition(n.StartPosition) {
+		lines[n.StartPosition.Line] = true
+	}
+	if uastutil.ValidPosition(n.EndPosition) {
+		lines[n.EndPosition.Line] = true
+	}
+	for _, child := range n.Children {
+		collectCodeLines(child, lines)
+	}
+}
+
+// commentLines returns the number of lines of the comments within the span.
+func commentLines(comments []*uast.Node, span Span) int {
+	lines := make(map[uint32]bool)
+	for _, comment := range comments {
+		s := NodeSpan(comment)
+		if s.StartLine < span.StartLine || s.EndLine > span.EndLine || s.StartLine == 0 {
+			continue
+		}
+		for line := s.StartLine; line <= s.EndLine; line++ {
+			lines[line] = true
+		}
+	}
+	return len(lines)
+}
@@ -0,0 +1,86 @@
+package tools
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/bblfsh/sdk.v1/uast"
+)
+
+func TestMaintainabilityFixture(t *testing.T) {
+	require := require.New(t)
+
+	n := fixtureUAST(t, "fixtures/npath/someFuncs.java.json")
+	result, err := Maintainability{}.Analyze(context.Background(), n)
+	require.NoError(err)
+	withComments, err := Maintainability{Comments: true}.Analyze(context.Background(), n)
+	require.NoError(err)
+	halstead, err := Halstead{}.Analyze(context.Background(), n)
+	require.NoError(err)
+	cyclomatic, err := CyclomaticComplexity{}.Analyze(context.Background(), n)
+	require.NoError(err)
+
+	functions := result.(*MaintainabilityResult).Functions
+	require.Len(functions, 6)
+	for i, f := range functions {
+		require.Equal(halstead.(*HalsteadResult).Functions[i].Volume, f.Volume, f.Name)
+		require.Equal(cyclomatic.(*CyclomaticResult).Functions[i].Complexity, f.Cyclomatic, f.Name)
+	}
+
+	// int min; if (n1 > n2) min = n2; else min = n1; return min;
+	f := functions[0]
+	require.Equal("Code.minFunction(int,int)", f.Name)
+	require.Equal(7, f.Lines)
+	expect := 171 - 5.2*math.Log(f.Volume) - 0.23*float64(f.Cyclomatic) - 16.2*math.Log(7)
+	require.InDelta(expect, f.Index, 1e-9)
+	require.InDelta(expect*100/171, f.Normalized, 1e-9)
+	require.Equal(*f, *withComments.(*MaintainabilityResult).Functions[0])
+
+	// the comments of reverse are children of the file
+	f = functions[2]
+	commented := withComments.(*MaintainabilityResult).Functions[2]
+	require.Equal("Code.reverse", commented.Name)
+	require.Equal(0, f.CommentLines)
+	require.Equal(3, commented.CommentLines)
+	require.Equal(12, commented.Lines)
+	term := 50 * math.Sin(math.Sqrt(2.4*20*math.Pi/180))
+	require.InDelta(f.Index+term, commented.Index, 1e-9)
+}
+
+func TestMaintainabilityBounds(t *testing.T) {
+	require := require.New(t)
+
+	line := func(l uint32) *uast.Position { return &uast.Position{Line: l, Col: 1} }
+	comment := func(l uint32) *uast.Node {
+		return &uast.Node{InternalType: "comment", Roles: []uast.Role{uast.Comment}, StartPosition: line(l), EndPosition: line(l)}
+	}
+	n := &uast.Node{InternalType: "module", Children: []*uast.Node{
+		{InternalType: "func", Roles: []uast.Role{uast.Function, uast.Declaration}, StartPosition: line(1), EndPosition: line(12), Children: []*uast.Node{
+			{InternalType: "name", Roles: []uast.Role{uast.Function, uast.Name}, Token: "f", StartPosition: line(1)},
+			{InternalType: "body", Roles: []uast.Role{uast.Function, uast.Body}, Children: []*uast.Node{
+				comment(2), comment(3), comment(4), comment(5), comment(6),
+				comment(7), comment(8), comment(9), comment(10), comment(11),
+			}},
+		}},
+		{InternalType: "func", Roles: []uast.Role{uast.Function, uast.Declaration}, Children: []*uast.Node{
+			{InternalType: "body", Roles: []uast.Role{uast.Function, uast.Body}},
+		}},
+	}}
+
+	result, err := Maintainability{Comments: true}.Analyze(context.Background(), n)
+	require.NoError(err)
+	functions := result.(*MaintainabilityResult).Functions
+	require.Len(functions, 2)
+
+	// most of the lines are comments, so the index exceeds 171
+	require.Equal(2, functions[0].Lines)
+	require.Equal(10, functions[0].CommentLines)
+	require.True(functions[0].Index > 171)
+	require.Equal(100.0, functions[0].Normalized)
+
+	// no volume nor lines
+	require.Equal(0, functions[1].Lines)
+	require.InDelta(171-0.23, functions[1].Index, 1e-9)
+}
@@ -0,0 +1,129 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+
+	"gopkg.in/bblfsh/sdk.v1/uast"
+)
+
+type Nesting struct{}
+
+// NestingData is the nesting depth of the control structures of a function.
+type NestingData struct {
+	Name     string `json:"name"`
+	MaxDepth int    `json:"max_depth"`
+	// AverageDepth is the average depth of all the control structures, zero
+	// if there are none.
+	AverageDepth float64 `json:"average_depth"`
+	// Deepest is the span of the first control structure at the maximum
+	// depth.
+	Deepest Span `json:"deepest"`
+	Span
+}
+
+// NestingResult is the result of the Nesting tool.
+type NestingResult struct {
+	Functions []*NestingData `json:"functions"`
+}
+
+// Analyze returns a *NestingResult with the nesting depth of every function
+// in the node.
+func (nt Nesting) Analyze(ctx context.Context, n *uast.Node) (Result, error) {
+	return &NestingResult{Functions: NestingDepth(n)}, nil
+}
+
+func (nd *NestingData) String() string {
+	return fmt.Sprintf("FuncName:%s, MaxDepth:%d, AverageDepth:%.2f, Deepest:%s, Position:%s\n",
+		nd.Name, nd.MaxDepth, nd.AverageDepth, nd.Deepest, nd.Span)
+}
+
+// NestingDepth computes the nesting depth of the control structures of the
+// functions in a *uast.Node: if, switch, loops and try. The ones directly in
+// the body of the function have depth one, and every structure in them adds
+// one. The else if of a chain have the same depth as the first if.
+//
+// The functions are found as in NPathComplexity. The nested functions are
+// only reported on their own, so their structures don't count for the ones
+// declaring them.
+func NestingDepth(n *uast.Node) []*NestingData {
+	var result []*NestingData
+	for _, function := range functions(n) {
+		v := &nestingVisitor{}
+		v.visitChildren(function.body, 0)
+
+		data := &NestingData{Name: function.name, MaxDepth: v.max, Deepest: v.deepest, Span: function.span()}
+		if v.structures > 0 {
+			data.AverageDepth = float64(v.total) / float64(v.structures)
+		}
+		result = append(result, data)
+	}
+	return result
+}
+
+// nestingVisitor finds the depth of the control structures of a function.
+type nestingVisitor struct {
+	max     int
+	deepest Span
+	// total is the sum of the depths of the structures.
+	total      int
+	structures int
+}
+
+func (v *nestingVisitor) visit(n *uast.Node, depth int) {
+	switch {
+	case isFunction(n):
+		return
+	case isIfStatement(n):
+		v.structure(n, depth+1)
+		v.visitIf(n, depth+1)
+	case isControlStructure(n):
+		v.structure(n, depth+1)
+		v.visitChildren(n, depth+1)
+	default:
+		v.visitChildren(n, depth)
+	}
+}
+
+func (v *nestingVisitor) visitChildren(n *uast.Node, depth int) {
+	for _, child := range n.Children {
+		v.visit(child, depth)
+	}
+}
+
+// visitIf visits the children of an if statement at its depth, but the if
+// of an else if, which is at the same depth as the if statement.
+func (v *nestingVisitor) visitIf(n *uast.Node, depth int) {
+	for _, child := range n.Children {
+		if !containsRoles(child, []uast.Role{uast.If, uast.Else}, nil) {
+			v.visit(child, depth)
+			continue
+		}
+
+		if elif := elseIf(child); elif != nil {
+			v.structure(elif, depth)
+			v.visitIf(elif, depth)
+		} else {
+			v.visit(child, depth)
+		}
+	}
+}
+
+func (v *nestingVisitor) structure(n *uast.Node, depth int) {
+	v.total += depth
+	v.structures++
+	if depth > v.max {
+		v.max = depth
+		v.deepest = NodeSpan(n)
+	}
+}
+
+// isControlStructure reports whether n is a switch, a loop or a try
+// statement, not the blocks annotated as their bodies.
+func isControlStructure(n *uast.Node) bool {
+	return containsRoles(n, []uast.Role{uast.Statement, uast.Switch}, []uast.Role{uast.Case, uast.Default, uast.Body}) ||
+		containsRoles(n, []uast.Role{uast.Statement, uast.For}, []uast.Role{uast.Body}) ||
+		containsRoles(n, []uast.Role{uast.Statement, uast.While}, []uast.Role{uast.Body}) ||
+		containsRoles(n, []uast.Role{uast.Statement, uast.DoWhile}, []uast.Role{uast.Body}) ||
+		containsRoles(n, []uast.Role{uast.Statement, uast.Try}, []uast.Role{uast.Body, uast.Catch, uast.Finally})
+}
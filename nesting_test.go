@@ -0,0 +1,80 @@
+package tools
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/bblfsh/sdk.v1/uast"
+)
+
+func TestNestingFixture(t *testing.T) {
+	require := require.New(t)
+
+	result, err := Nesting{}.Analyze(context.Background(), fixtureUAST(t, "fixtures/npath/cognitive.java.json"))
+	require.NoError(err)
+
+	var names []string
+	var maxDepths []int
+	var averages []float64
+	var deepest []uint32
+	for _, f := range result.(*NestingResult).Functions {
+		names = append(names, f.Name)
+		maxDepths = append(maxDepths, f.MaxDepth)
+		averages = append(averages, f.AverageDepth)
+		deepest = append(deepest, f.Deepest.StartLine)
+	}
+	require.Equal([]string{"Code.sumOfPrimes(int)", "Code.words(int)", "Code.fact(int)", "Code.sign(int)"}, names)
+	// the else if of fact is at the same depth as its if, the while of sign
+	// is nested in the catch of its try
+	require.Equal([]int{3, 1, 1, 2}, maxDepths)
+	require.Equal([]float64{2, 1, 1, 1.5}, averages)
+	require.Equal([]uint32{7, 17, 28, 41}, deepest)
+}
+
+func TestNestingShapes(t *testing.T) {
+	require := require.New(t)
+
+	ifStmt := func(children ...*uast.Node) *uast.Node {
+		cond := &uast.Node{InternalType: "cond", Roles: []uast.Role{uast.Expression, uast.If, uast.Condition}}
+		return &uast.Node{InternalType: "if", Roles: []uast.Role{uast.Statement, uast.If}, Children: append([]*uast.Node{cond}, children...)}
+	}
+	orElse := func(children ...*uast.Node) *uast.Node {
+		return &uast.Node{InternalType: "orelse", Roles: []uast.Role{uast.If, uast.Else}, Children: children}
+	}
+	loop := func(children ...*uast.Node) *uast.Node {
+		return &uast.Node{InternalType: "for", Roles: []uast.Role{uast.Statement, uast.For}, Children: []*uast.Node{
+			{InternalType: "body", Roles: []uast.Role{uast.For, uast.Body, uast.Statement}, Children: children},
+		}}
+	}
+	lambda := func(children ...*uast.Node) *uast.Node {
+		return &uast.Node{InternalType: "Lambda", Roles: []uast.Role{uast.Expression}, Children: children}
+	}
+	statement := &uast.Node{InternalType: "statement", Roles: []uast.Role{uast.Statement}}
+
+	cases := []struct {
+		name    string
+		n       *uast.Node
+		max     []int
+		average []float64
+	}{
+		{"no structures", cognitiveBody(statement), []int{0}, []float64{0}},
+		// the loop body is not a loop on its own
+		{"nested loops", cognitiveBody(loop(loop(statement)), loop()), []int{2}, []float64{4.0 / 3}},
+		{"elif chain", cognitiveBody(ifStmt(orElse(ifStmt(orElse(ifStmt(loop())))))), []int{2}, []float64{5.0 / 4}},
+		{"if in else", cognitiveBody(ifStmt(orElse(statement, ifStmt()))), []int{2}, []float64{1.5}},
+		// the structures of the lambda only count for the lambda itself
+		{"lambda", cognitiveBody(loop(lambda(ifStmt()))), []int{1, 1}, []float64{1, 1}},
+	}
+
+	for _, c := range cases {
+		var maxDepths []int
+		var averages []float64
+		for _, f := range NestingDepth(c.n) {
+			maxDepths = append(maxDepths, f.MaxDepth)
+			averages = append(averages, f.AverageDepth)
+		}
+		require.Equal(c.max, maxDepths, c.name)
+		require.InDeltaSlice(c.average, averages, 1e-9, c.name)
+	}
+}
@@ -1,223 +1,357 @@
 package tools
 
 import (
+	"context"
 	"fmt"
+	"strconv"
 
+	"github.com/bblfsh/tools/internal/uastutil"
 	"gopkg.in/bblfsh/sdk.v1/uast"
 )
 
-type NPath struct{}
+type NPath struct {
+	// ExcludeNested makes the nested functions, including lambdas and
+	// closures, not count towards the complexity of the function declaring
+	// them. They are always reported as functions on their own.
+	ExcludeNested bool
+}
 
 type NPathData struct {
-	Name       string
-	Complexity int
+	Name       string `json:"name"`
+	Complexity int    `json:"complexity"`
+	// Capped is set when the complexity is too big to be represented, then
+	// Complexity is the maximum int.
+	Capped bool `json:"capped,omitempty"`
+	// Warnings are the unexpected nodes found in the function, which make
+	// the complexity less accurate.
+	Warnings []*Warning `json:"warnings,omitempty"`
+	Span
+}
+
+// NPathResult is the result of the NPath tool.
+type NPathResult struct {
+	Functions []*NPathData `json:"functions"`
 }
 
 func (np NPath) Exec(n *uast.Node) error {
-	result := NPathComplexity(n)
+	result := np.complexity(n)
 	fmt.Println(result)
 	return nil
 }
 
+// Analyze returns a *NPathResult with the NPath complexity of every function
+// in the node.
+func (np NPath) Analyze(ctx context.Context, n *uast.Node) (Result, error) {
+	return &NPathResult{Functions: np.complexity(n)}, nil
+}
+
 func (nd *NPathData) String() string {
-	return fmt.Sprintf("FuncName:%s, Complexity:%d\n", nd.Name, nd.Complexity)
+	complexity := strconv.Itoa(nd.Complexity)
+	if nd.Capped {
+		complexity += " (capped)"
+	}
+	return fmt.Sprintf("FuncName:%s, Complexity:%s, Position:%s\n", nd.Name, complexity, nd.Span)
 }
 
 //Npath computes the NPath of functions in a *uast.Node.
 //
+//Functions are named after the namespaces and types enclosing them, followed by the
+//types of their arguments when known, so overloaded methods can be told apart.
+//
+//Statements missing an expected child, like an if without Then, are computed as
+//if it was empty and reported as warnings of the function.
+//
+//The complexity grows exponentially with the sequential statements, instead of
+//overflowing it saturates at the maximum int and the function is marked as capped.
+//
 //PMD is considered the reference implementation to assert correctness.
 //See: https://pmd.github.io/pmd-5.7.0/pmd-java/xref/net/sourceforge/pmd/lang/java/rule/codesize/NPathComplexityRule.html
 func NPathComplexity(n *uast.Node) []*NPathData {
+	return NPath{}.complexity(n)
+}
+
+func (np NPath) complexity(n *uast.Node) []*NPathData {
 	var result []*NPathData
-	var funcs []*uast.Node
-	var names []string
+	v := &npathVisitor{excludeNested: np.ExcludeNested}
+	for _, function := range functions(n) {
+		v.capped, v.warnings = false, nil
+		npath := v.visitFunctionBody(function.body)
+		result = append(result, &NPathData{
+			Name:       function.name,
+			Complexity: npath,
+			Capped:     v.capped,
+			Warnings:   v.warnings,
+			Span:       function.span(),
+		})
+	}
 
-	if containsRoles(n, []uast.Role{uast.Function, uast.Body}, nil) {
-		funcs = append(funcs, n)
-		names = append(names, "NoName")
-	} else {
-		funcDecs := deepChildrenOfRoles(n, []uast.Role{uast.Function, uast.Declaration}, []uast.Role{uast.Argument})
-		for _, funcDec := range funcDecs {
-			if containsRoles(funcDec, []uast.Role{uast.Function, uast.Name}, nil) {
-				names = append(names, funcDec.Token)
-			}
-			childNames := childrenOfRoles(funcDec, []uast.Role{uast.Function, uast.Name}, nil)
-			if len(childNames) > 0 {
-				names = append(names, childNames[0].Token)
-			}
-			childFuncs := childrenOfRoles(funcDec, []uast.Role{uast.Function, uast.Body}, nil)
-			if len(childFuncs) > 0 {
-				funcs = append(funcs, childFuncs[0])
-			}
-		}
+	return result
+}
+
+// maxNPath is the value the NPath complexity saturates at.
+const maxNPath = int(^uint(0) >> 1)
+
+// npathVisitor computes the NPath complexity of the nodes.
+type npathVisitor struct {
+	excludeNested bool
+	// capped is set when a complexity saturates at maxNPath.
+	capped   bool
+	warnings []*Warning
+}
+
+// requiredChild returns the first child of n with the given roles. If there
+// is none, a warning is added and an empty node is returned instead, so the
+// missing child counts as an empty block or a simple condition.
+func (v *npathVisitor) requiredChild(n *uast.Node, statement, part string, roles ...uast.Role) *uast.Node {
+	if children := childrenOfRoles(n, roles, nil); len(children) > 0 {
+		return children[0]
 	}
-	for i, function := range funcs {
-		npath := visitFunctionBody(function)
-		result = append(result, &NPathData{Name: names[i], Complexity: npath})
+	v.warnings = append(v.warnings, newWarning(n, "%s without %s", statement, part))
+	return &uast.Node{}
+}
+
+// add returns a + b, or maxNPath if it overflows.
+func (v *npathVisitor) add(a, b int) int {
+	if a > maxNPath-b {
+		v.capped = true
+		return maxNPath
 	}
+	return a + b
+}
 
-	return result
+// mult returns a * b, or maxNPath if it overflows.
+func (v *npathVisitor) mult(a, b int) int {
+	if a != 0 && b > maxNPath/a {
+		v.capped = true
+		return maxNPath
+	}
+	return a * b
 }
 
-func visitorSelector(n *uast.Node) int {
+func (v *npathVisitor) visitorSelector(n *uast.Node) int {
+	if v.excludeNested && isFunction(n) {
+		return 1
+	}
 	if containsRoles(n, []uast.Role{uast.Statement, uast.If}, []uast.Role{uast.Then, uast.Else}) {
-		return visitIf(n)
+		return v.visitIf(n)
 	}
 	if containsRoles(n, []uast.Role{uast.Statement, uast.While}, nil) {
-		return visitWhile(n)
+		return v.visitWhile(n)
 	}
-	if containsRoles(n, []uast.Role{uast.Statement, uast.Switch}, nil) {
-		return visitSwitch(n)
+	if containsRoles(n, []uast.Role{uast.Statement, uast.Switch}, []uast.Role{uast.Case, uast.Default, uast.Body}) {
+		return v.visitSwitch(n)
 	}
 	if containsRoles(n, []uast.Role{uast.Statement, uast.DoWhile}, nil) {
-		return visitDoWhile(n)
+		return v.visitDoWhile(n)
 	}
 	if containsRoles(n, []uast.Role{uast.Statement, uast.For}, nil) {
-		return visitFor(n)
+		return v.visitFor(n)
 	}
 	if containsRoles(n, []uast.Role{uast.Statement, uast.Return}, nil) {
-		return visitReturn(n)
+		return v.visitReturn(n)
 	}
 	if containsRoles(n, []uast.Role{uast.Statement, uast.Try}, nil) {
-		return visitTry(n)
+		return v.visitTry(n)
+	}
+	if isJump(n) {
+		return v.visitJump(n)
 	}
-	return visitNotCompNode(n)
+	if isConditionalExpr(n) {
+		return v.visitConditionalExpr(n)
+	}
+	return v.visitNotCompNode(n)
 }
 
-func complexityMultOf(n *uast.Node) int {
+func (v *npathVisitor) complexityMultOf(n *uast.Node) int {
 	npath := 1
 	for _, child := range n.Children {
-		npath *= visitorSelector(child)
+		npath = v.mult(npath, v.visitorSelector(child))
 	}
 	return npath
 }
 
-func visitFunctionBody(n *uast.Node) int {
-	return complexityMultOf(n)
+func (v *npathVisitor) visitFunctionBody(n *uast.Node) int {
+	// the body of a lambda can be just a conditional expression
+	if isConditionalExpr(n) {
+		return v.visitConditionalExpr(n)
+	}
+	return v.complexityMultOf(n)
 }
 
-func visitNotCompNode(n *uast.Node) int {
-	return complexityMultOf(n)
+func (v *npathVisitor) visitNotCompNode(n *uast.Node) int {
+	return v.complexityMultOf(n)
 }
 
-func visitIf(n *uast.Node) int {
+func (v *npathVisitor) visitIf(n *uast.Node) int {
 	// (npath of if + npath of else (or 1) + bool_comp of if) * npath of next
 	npath := 0
-	ifThen := childrenOfRoles(n, []uast.Role{uast.If, uast.Then}, nil)
-	ifCondition := childrenOfRoles(n, []uast.Role{uast.If, uast.Condition}, nil)
+	ifThen := v.requiredChild(n, "if", "Then", uast.If, uast.Then)
+	ifCondition := v.requiredChild(n, "if", "Condition", uast.If, uast.Condition)
 	ifElse := childrenOfRoles(n, []uast.Role{uast.If, uast.Else}, nil)
 
 	if len(ifElse) > 0 {
-		npath += complexityMultOf(ifElse[0])
+		npath = v.add(npath, v.complexityMultOf(ifElse[0]))
 	} else {
 		npath++
 	}
-	npath *= complexityMultOf(ifThen[0])
-	npath += expressionComp(ifCondition[0])
+	npath = v.mult(npath, v.complexityMultOf(ifThen))
+	npath = v.add(npath, expressionComp(ifCondition))
 
 	return npath
 }
 
-func visitWhile(n *uast.Node) int {
+func (v *npathVisitor) visitWhile(n *uast.Node) int {
 	// (npath of while + bool_comp of while + npath of else (or 1)) * npath of next
 	npath := 0
-	whileCondition := childrenOfRoles(n, []uast.Role{uast.While, uast.Condition}, nil)
-	whileBody := childrenOfRoles(n, []uast.Role{uast.While, uast.Body}, nil)
+	whileCondition := v.requiredChild(n, "while", "Condition", uast.While, uast.Condition)
+	whileBody := v.requiredChild(n, "while", "Body", uast.While, uast.Body)
 	whileElse := childrenOfRoles(n, []uast.Role{uast.While, uast.Else}, nil)
 	// Some languages like python can have an else in a while loop
 	if len(whileElse) > 0 {
-		npath += complexityMultOf(whileElse[0])
+		npath = v.add(npath, v.complexityMultOf(whileElse[0]))
 	} else {
 		npath++
 	}
 
-	npath *= complexityMultOf(whileBody[0])
-	npath += expressionComp(whileCondition[0])
+	npath = v.mult(npath, v.complexityMultOf(whileBody))
+	npath = v.add(npath, expressionComp(whileCondition))
 
 	return npath
 }
 
-func visitDoWhile(n *uast.Node) int {
+func (v *npathVisitor) visitDoWhile(n *uast.Node) int {
 	// (npath of do + bool_comp of do + 1) * npath of next
 	npath := 1
-	doWhileCondition := childrenOfRoles(n, []uast.Role{uast.DoWhile, uast.Condition}, nil)
-	doWhileBody := childrenOfRoles(n, []uast.Role{uast.DoWhile, uast.Body}, nil)
+	doWhileCondition := v.requiredChild(n, "do-while", "Condition", uast.DoWhile, uast.Condition)
+	doWhileBody := v.requiredChild(n, "do-while", "Body", uast.DoWhile, uast.Body)
 
-	npath *= complexityMultOf(doWhileBody[0])
-	npath += expressionComp(doWhileCondition[0])
+	npath = v.mult(npath, v.complexityMultOf(doWhileBody))
+	npath = v.add(npath, expressionComp(doWhileCondition))
 
 	return npath
 }
 
-func visitFor(n *uast.Node) int {
+func (v *npathVisitor) visitFor(n *uast.Node) int {
 	// (npath of for + bool_comp of for + 1) * npath of next
 	npath := 1
 	forBody := childrenOfRoles(n, []uast.Role{uast.For, uast.Body}, nil)
 	if len(forBody) > 0 {
-		npath *= complexityMultOf(forBody[0])
+		npath = v.mult(npath, v.complexityMultOf(forBody[0]))
 	}
-	npath++
+	npath = v.add(npath, 1)
 	return npath
 }
 
-func visitReturn(n *uast.Node) int {
-	if aux := expressionComp(n); aux != 1 {
-		return aux - 1
+func (v *npathVisitor) visitReturn(n *uast.Node) int {
+	// bool_comp of return + npath of its conditional expressions (if greater than 1), or 1
+	npath := expressionComp(n) - 1
+	if conditionalComp := v.complexityMultOf(n); conditionalComp > 1 {
+		npath = v.add(npath, conditionalComp)
+	}
+	if npath > 0 {
+		return npath
 	}
 	return 1
 }
 
-func visitSwitch(n *uast.Node) int {
-	caseDefault := childrenOfRoles(n, []uast.Role{uast.Switch, uast.Default}, nil)
-	switchCases := childrenOfRoles(n, []uast.Role{uast.Statement, uast.Switch, uast.Case}, []uast.Role{uast.Body})
-	npath := 0
-
-	if len(caseDefault) > 0 {
-		npath += complexityMultOf(caseDefault[0])
-	} else {
-		npath++
+func (v *npathVisitor) visitSwitch(n *uast.Node) int {
+	// bool_comp of switch + sum(npath of case range)
+	// A case range goes from a label to the next one, its npath is the
+	// product of its statements, so fall-through labels count as 1.
+	var expressions []*uast.Node
+	npath, caseRange, labels := 0, 0, 0
+	for _, child := range uastutil.SwitchChildren(n) {
+		switch {
+		case uastutil.IsSwitchLabel(child):
+			npath = v.add(npath, caseRange)
+			// some drivers put the statements of the case in the label
+			caseRange = v.complexityMultOf(child)
+			labels++
+		case labels == 0:
+			expressions = append(expressions, child)
+		default:
+			caseRange = v.mult(caseRange, v.visitorSelector(child))
+		}
 	}
-	for _, switchCase := range switchCases {
-		npath += complexityMultOf(switchCase)
+	npath = v.add(npath, caseRange)
+	if npath == 0 {
+		// a switch without labels is just its expression
+		npath = 1
 	}
-	return npath
+
+	boolComp := expressionComp(&uast.Node{Children: expressions}) - 1
+	return v.add(boolComp, npath)
+}
+
+func (v *npathVisitor) visitJump(n *uast.Node) int {
+	// break, continue and goto don't add paths, even with a label
+	return 1
 }
 
-func visitTry(n *uast.Node) int {
+func isJump(n *uast.Node) bool {
+	return containsRoles(n, []uast.Role{uast.Statement, uast.Break}, nil) ||
+		containsRoles(n, []uast.Role{uast.Statement, uast.Continue}, nil) ||
+		containsRoles(n, []uast.Role{uast.Statement, uast.Goto}, nil)
+}
+
+func (v *npathVisitor) visitTry(n *uast.Node) int {
 	/*
 		In pmd they decided the complexity of a try is the summatory of the complexity
 		of the try body, catch body and finally body.I don't think this is the most acurate way
 		of doing this.
 	*/
 
-	tryBody := childrenOfRoles(n, []uast.Role{uast.Try, uast.Body}, nil)
+	tryBody := v.requiredChild(n, "try", "Body", uast.Try, uast.Body)
 	tryCatch := childrenOfRoles(n, []uast.Role{uast.Try, uast.Catch}, nil)
 	tryFinaly := childrenOfRoles(n, []uast.Role{uast.Try, uast.Finally}, nil)
 
 	catchComp := 0
 	if len(tryCatch) > 0 {
 		for _, catch := range tryCatch {
-			catchComp += complexityMultOf(catch)
+			catchComp = v.add(catchComp, v.complexityMultOf(catch))
 		}
 	}
 	finallyComp := 0
 	if len(tryFinaly) > 0 {
-		finallyComp = complexityMultOf(tryFinaly[0])
+		finallyComp = v.complexityMultOf(tryFinaly[0])
 	}
-	npath := complexityMultOf(tryBody[0]) + catchComp + finallyComp
+	npath := v.add(v.add(v.complexityMultOf(tryBody), catchComp), finallyComp)
 
 	return npath
 }
 
-func visitConditionalExpr(n *uast.Node) {
-	// TODO ternary operators are not defined on the UAST yet
+func (v *npathVisitor) visitConditionalExpr(n *uast.Node) int {
+	// npath of condition * npath of then * npath of else + 2
+	return v.add(v.complexityMultOf(n), 2)
 }
 
-func expressionComp(n *uast.Node) int {
-	orCount := deepCountChildrenOfRoles(n, []uast.Role{uast.Operator, uast.Boolean, uast.And}, nil)
-	andCount := deepCountChildrenOfRoles(n, []uast.Role{uast.Operator, uast.Boolean, uast.Or}, nil)
+// conditionalTypes are the internal types of conditional expressions for the
+// drivers that don't annotate them with roles.
+var conditionalTypes = map[string]bool{
+	"ConditionalExpression": true, // java, javascript, csharp
+	"Expr_Ternary":          true, // php
+	"IfExp":                 true, // python
+}
+
+// isConditionalExpr reports whether n is a conditional expression, like the
+// ternary operator "a ? b : c": an expression, not a statement, with a
+// condition, a then and an else.
+func isConditionalExpr(n *uast.Node) bool {
+	if conditionalTypes[n.InternalType] {
+		return true
+	}
+	if containsRoles(n, []uast.Role{uast.Statement}, nil) {
+		return false
+	}
+	return countChildrenOfRoles(n, []uast.Role{uast.Condition}, nil) > 0 &&
+		countChildrenOfRoles(n, []uast.Role{uast.Then}, nil) > 0 &&
+		countChildrenOfRoles(n, []uast.Role{uast.Else}, nil) > 0
+}
 
-	return orCount + andCount + 1
+// expressionComp returns the number of conditions of the boolean expressions
+// in n, including n itself, one if there are no short-circuit operators.
+func expressionComp(n *uast.Node) int {
+	return shortCircuitConditions(n) + 1
 }
 
 func containsRoles(n *uast.Node, andRoles []uast.Role, notRoles []uast.Role) bool {
@@ -2,7 +2,9 @@ package tools
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
+	"math/rand"
 	"os"
 	"testing"
 
@@ -113,6 +115,54 @@ func TestExpresionComplex(t *testing.T) {
 	require.Equal(expect, result)
 }
 
+// booleanShape is a boolean expression as represented by some driver, with
+// the number of conditions it adds.
+type booleanShape struct {
+	name       string
+	n          *uast.Node
+	conditions int
+}
+
+func booleanShapes() []booleanShape {
+	and := func(children ...*uast.Node) *uast.Node {
+		return &uast.Node{InternalType: "and", Roles: []uast.Role{uast.Expression, uast.Operator, uast.Boolean, uast.And}, Children: children}
+	}
+	or := func(children ...*uast.Node) *uast.Node {
+		return &uast.Node{InternalType: "or", Roles: []uast.Role{uast.Expression, uast.Operator, uast.Boolean, uast.Or}, Children: children}
+	}
+	expr := func(children ...*uast.Node) *uast.Node {
+		return &uast.Node{InternalType: "expr", Roles: []uast.Role{uast.Expression}, Children: children}
+	}
+	id := func(token string) *uast.Node {
+		return &uast.Node{InternalType: "id", Roles: []uast.Role{uast.Expression, uast.Identifier}, Token: token}
+	}
+
+	return []booleanShape{
+		// java, go, javascript: a && b && c
+		{"binary", and(and(id("a"), id("b")), id("c")), 2},
+		// java extended operands: a && b && c
+		{"n-ary operator", and(id("a"), id("b"), id("c")), 2},
+		// python: a and b and c
+		{"python", expr(and(), id("a"), id("b"), id("c")), 2},
+		// python: a and (b or c)
+		{"python nested", expr(and(), id("a"), expr(or(), id("b"), id("c"))), 2},
+		// lisp: (or a (and b c d))
+		{"prefix", expr(or(), id("a"), expr(and(), id("b"), id("c"), id("d"))), 3},
+		// infix tokens: a && b || c
+		{"flat infix", expr(id("a"), and(), id("b"), or(), id("c")), 2},
+		// an operator without operands
+		{"no operands", and(), 0},
+		{"missing operand", and(id("a")), 1},
+		{"no boolean", expr(id("a"), id("b")), 0},
+	}
+}
+
+func TestExpressionCompShapes(t *testing.T) {
+	for _, shape := range booleanShapes() {
+		require.Equal(t, shape.conditions+1, expressionComp(shape.n), shape.name)
+	}
+}
+
 func TestNPathComplexity(t *testing.T) {
 	require := require.New(t)
 	var result []int
@@ -713,8 +763,416 @@ func TestRealUAST(t *testing.T) {
 		}
 	}
 
-	expect := []int{2, 2, 2, 2, 2, 6, 2, 6, 3, 5, 4}
+	// the condition of the do-while is itself an &&
+	expect := []int{2, 3, 2, 2, 2, 6, 2, 6, 3, 5, 4}
 
 	require.Equal(expect, result)
 
 }
+
+func TestNPathAnalyze(t *testing.T) {
+	require := require.New(t)
+
+	n := &uast.Node{InternalType: "module", Children: []*uast.Node{
+		{InternalType: "func", Roles: []uast.Role{uast.Function, uast.Declaration}, Children: []*uast.Node{
+			{InternalType: "name", Roles: []uast.Role{uast.Function, uast.Name}, Token: "foo"},
+			{InternalType: "body", Roles: []uast.Role{uast.Function, uast.Body}},
+		}},
+	}}
+
+	result, err := NPath{}.Analyze(context.Background(), n)
+	require.NoError(err)
+	require.Equal(&NPathResult{Functions: []*NPathData{{Name: "foo", Complexity: 1}}}, result)
+}
+
+func TestNPathQualifiedNames(t *testing.T) {
+	require := require.New(t)
+
+	method := func(name string, argTypes ...string) *uast.Node {
+		m := &uast.Node{InternalType: "MethodDeclaration", Roles: []uast.Role{uast.Visibility, uast.Package, uast.Declaration, uast.Function}, Children: []*uast.Node{
+			{InternalType: "SimpleName", Roles: []uast.Role{uast.Expression, uast.Identifier, uast.Function, uast.Name}, Token: name},
+		}}
+		for _, argType := range argTypes {
+			m.Children = append(m.Children, &uast.Node{InternalType: "SingleVariableDeclaration", Roles: []uast.Role{uast.Function, uast.Argument, uast.Declaration}, Children: []*uast.Node{
+				{InternalType: "SimpleType", Roles: []uast.Role{uast.Type}, Children: []*uast.Node{
+					{InternalType: "SimpleName", Roles: []uast.Role{uast.Expression, uast.Identifier}, Token: argType},
+				}},
+				{InternalType: "SimpleName", Roles: []uast.Role{uast.Expression, uast.Identifier, uast.Function, uast.Name}, Token: "arg"},
+			}})
+		}
+		m.Children = append(m.Children, &uast.Node{InternalType: "Block", Roles: []uast.Role{uast.Function, uast.Body, uast.Statement, uast.Block}})
+		return m
+	}
+	class := func(name string, children ...*uast.Node) *uast.Node {
+		return &uast.Node{InternalType: "TypeDeclaration", Roles: []uast.Role{uast.Visibility, uast.Package, uast.Declaration, uast.Type}, Children: append([]*uast.Node{
+			{InternalType: "SimpleName", Roles: []uast.Role{uast.Expression, uast.Identifier}, Token: name},
+		}, children...)}
+	}
+
+	n := &uast.Node{InternalType: "CompilationUnit", Roles: []uast.Role{uast.File}, Children: []*uast.Node{
+		{InternalType: "PackageDeclaration", Roles: []uast.Role{uast.Package, uast.Declaration}, Children: []*uast.Node{
+			{InternalType: "QualifiedName", Roles: []uast.Role{uast.Expression, uast.Identifier, uast.Qualified}, Children: []*uast.Node{
+				{InternalType: "SimpleName", Roles: []uast.Role{uast.Expression, uast.Identifier}, Token: "org"},
+				{InternalType: "SimpleName", Roles: []uast.Role{uast.Expression, uast.Identifier}, Token: "example"},
+			}},
+		}},
+		class("Foo",
+			method("run"),
+			method("run", "int"),
+			method("run", "String"),
+			class("Inner", method("run")),
+		),
+		class("Bar", method("run")),
+	}}
+
+	var names []string
+	for _, v := range NPathComplexity(n) {
+		names = append(names, v.Name)
+	}
+	expect := []string{
+		"org.example.Foo.run",
+		"org.example.Foo.run(int)",
+		"org.example.Foo.run(String)",
+		"org.example.Foo.Inner.run",
+		"org.example.Bar.run",
+	}
+	require.Equal(expect, names)
+
+	body := &uast.Node{InternalType: "body", Roles: []uast.Role{uast.Function, uast.Body},
+		StartPosition: &uast.Position{Offset: 10, Line: 42, Col: 1},
+	}
+	require.Equal("NoName@L42", NPathComplexity(body)[0].Name)
+}
+
+func TestNPathNestedFunctions(t *testing.T) {
+	require := require.New(t)
+
+	ifStmt := func() *uast.Node {
+		return &uast.Node{InternalType: "If", Roles: []uast.Role{uast.Statement, uast.If}, Children: []*uast.Node{
+			{InternalType: "Condition", Roles: []uast.Role{uast.If, uast.Condition}},
+			{InternalType: "Then", Roles: []uast.Role{uast.If, uast.Then}},
+		}}
+	}
+	inner := &uast.Node{InternalType: "FunctionDef", Roles: []uast.Role{uast.Function, uast.Declaration}, Children: []*uast.Node{
+		{InternalType: "Name", Roles: []uast.Role{uast.Function, uast.Name}, Token: "inner"},
+		{InternalType: "Body", Roles: []uast.Role{uast.Function, uast.Body}, Children: []*uast.Node{ifStmt()}},
+	}}
+	// lambda passed as an argument, with an expression as body
+	lambda := &uast.Node{InternalType: "LambdaExpression", Roles: []uast.Role{uast.Call, uast.Argument},
+		StartPosition: &uast.Position{Offset: 50, Line: 5, Col: 10},
+		Children: []*uast.Node{
+			{InternalType: "Parameter", Roles: []uast.Role{uast.Function, uast.Argument, uast.Declaration}},
+			ifStmt(),
+		},
+	}
+	outer := &uast.Node{InternalType: "FunctionDef", Roles: []uast.Role{uast.Function, uast.Declaration}, Children: []*uast.Node{
+		{InternalType: "Name", Roles: []uast.Role{uast.Function, uast.Name}, Token: "outer"},
+		{InternalType: "Body", Roles: []uast.Role{uast.Function, uast.Body}, Children: []*uast.Node{
+			ifStmt(),
+			inner,
+			{InternalType: "Call", Roles: []uast.Role{uast.Statement, uast.Call}, Children: []*uast.Node{lambda}},
+		}},
+	}}
+	n := &uast.Node{InternalType: "Module", Children: []*uast.Node{outer}}
+
+	names := func(data []*NPathData) ([]string, []int) {
+		var names []string
+		var complexities []int
+		for _, v := range data {
+			names = append(names, v.Name)
+			complexities = append(complexities, v.Complexity)
+		}
+		return names, complexities
+	}
+
+	resultNames, result := names(NPathComplexity(n))
+	require.Equal([]string{"outer", "outer$inner", "outer$lambda@L5"}, resultNames)
+	require.Equal([]int{8, 2, 2}, result)
+
+	resultNames, result = names(NPath{ExcludeNested: true}.complexity(n))
+	require.Equal([]string{"outer", "outer$inner", "outer$lambda@L5"}, resultNames)
+	require.Equal([]int{2, 2, 2}, result)
+}
+
+func TestNPathConditionalExpr(t *testing.T) {
+	require := require.New(t)
+
+	// a conditional expression only annotated with roles
+	ternary := func(roles []uast.Role, then *uast.Node) *uast.Node {
+		return &uast.Node{InternalType: "Ternary", Roles: roles, Children: []*uast.Node{
+			{InternalType: "Condition", Roles: []uast.Role{uast.Expression, uast.If, uast.Condition}},
+			then,
+			{InternalType: "Else", Roles: []uast.Role{uast.Expression, uast.If, uast.Else}},
+		}}
+	}
+	then := &uast.Node{InternalType: "Then", Roles: []uast.Role{uast.Expression, uast.If, uast.Then}}
+	andBool := &uast.Node{InternalType: "bool_and", Roles: []uast.Role{uast.Operator, uast.Boolean, uast.And}}
+
+	/*
+		x = c ? (c ? a : b) : d;
+		return c && d ? a : b;
+		Npath = 5 * (1 + 3)
+	*/
+	n := &uast.Node{InternalType: "Function declaration body", Roles: []uast.Role{uast.Function, uast.Body}, Children: []*uast.Node{
+		{InternalType: "Assign", Roles: []uast.Role{uast.Statement, uast.Assignment}, Children: []*uast.Node{
+			ternary([]uast.Role{uast.Expression, uast.If}, ternary([]uast.Role{uast.Expression, uast.If, uast.Then}, then)),
+		}},
+		{InternalType: "Return", Roles: []uast.Role{uast.Statement, uast.Return}, Children: []*uast.Node{
+			{InternalType: "Ternary", Roles: []uast.Role{uast.Expression, uast.If}, Children: []*uast.Node{
+				{InternalType: "Condition", Roles: []uast.Role{uast.Expression, uast.If, uast.Condition}, Children: []*uast.Node{andBool}},
+				then,
+				{InternalType: "Else", Roles: []uast.Role{uast.Expression, uast.If, uast.Else}},
+			}},
+		}},
+	}}
+	require.Equal(20, NPathComplexity(n)[0].Complexity)
+
+	// if statements are not conditional expressions
+	require.False(isConditionalExpr(&uast.Node{InternalType: "If", Roles: []uast.Role{uast.Statement, uast.If}, Children: []*uast.Node{
+		{InternalType: "Condition", Roles: []uast.Role{uast.If, uast.Condition}},
+		{InternalType: "Then", Roles: []uast.Role{uast.If, uast.Then}},
+		{InternalType: "Else", Roles: []uast.Role{uast.If, uast.Else}},
+	}}))
+}
+
+func TestNPathConditionalFixtures(t *testing.T) {
+	require := require.New(t)
+
+	// the fixtures are hand-written, not recorded, see fixtures/README.md
+
+	cases := []struct {
+		file   string
+		names  []string
+		expect []int
+	}{
+		{"fixtures/npath/ternary.java.json", []string{"Code.max(int,int)", "Code.sign(int)", "Code.clamp(int,boolean)"}, []int{3, 5, 8}},
+		{"fixtures/npath/ternary.js.json", []string{"max", "label", "lambda@L10"}, []int{3, 5, 3}},
+		{"fixtures/npath/ternary.py.json", []string{"maximum", "sign", "parity"}, []int{3, 5, 9}},
+	}
+
+	for _, c := range cases {
+		file, err := os.Open(c.file)
+		require.NoError(err)
+
+		res := &protocol.ParseResponse{}
+		err = json.NewDecoder(bufio.NewReader(file)).Decode(res)
+		file.Close()
+		require.NoError(err)
+
+		var names []string
+		var result []int
+		for _, v := range NPathComplexity(res.UAST) {
+			names = append(names, v.Name)
+			result = append(result, v.Complexity)
+		}
+		require.Equal(c.names, names, c.file)
+		require.Equal(c.expect, result, c.file)
+	}
+}
+
+func TestNPathCapped(t *testing.T) {
+	require := require.New(t)
+
+	// a function with n sequential ifs has a NPath of 2^n
+	function := func(name string, n int) *uast.Node {
+		body := &uast.Node{InternalType: "Body", Roles: []uast.Role{uast.Function, uast.Body}}
+		for i := 0; i < n; i++ {
+			body.Children = append(body.Children, &uast.Node{InternalType: "If", Roles: []uast.Role{uast.Statement, uast.If}, Children: []*uast.Node{
+				{InternalType: "Condition", Roles: []uast.Role{uast.If, uast.Condition}},
+				{InternalType: "Then", Roles: []uast.Role{uast.If, uast.Then}},
+			}})
+		}
+		return &uast.Node{InternalType: "FunctionDef", Roles: []uast.Role{uast.Function, uast.Declaration}, Children: []*uast.Node{
+			{InternalType: "Name", Roles: []uast.Role{uast.Function, uast.Name}, Token: name},
+			body,
+		}}
+	}
+	n := &uast.Node{InternalType: "Module", Children: []*uast.Node{
+		function("small", 10),
+		function("huge", 100),
+		function("other", 3),
+	}}
+
+	result := NPathComplexity(n)
+	require.Len(result, 3)
+	require.Equal(1024, result[0].Complexity)
+	require.False(result[0].Capped)
+	require.Equal(maxNPath, result[1].Complexity)
+	require.True(result[1].Capped)
+	require.Contains(result[1].String(), "(capped)")
+	require.Equal(8, result[2].Complexity)
+	require.False(result[2].Capped)
+}
+
+func TestNPathMissingChildren(t *testing.T) {
+	require := require.New(t)
+
+	pos := &uast.Position{Offset: 120, Line: 12, Col: 3}
+	n := &uast.Node{InternalType: "Function declaration body", Roles: []uast.Role{uast.Function, uast.Body}, Children: []*uast.Node{
+		// if without Then
+		{InternalType: "If", Roles: []uast.Role{uast.Statement, uast.If}, StartPosition: pos, Children: []*uast.Node{
+			{InternalType: "Condition", Roles: []uast.Role{uast.If, uast.Condition}},
+		}},
+		// while without Condition nor Body
+		{InternalType: "While", Roles: []uast.Role{uast.Statement, uast.While}},
+		// do-while and try without children
+		{InternalType: "DoWhile", Roles: []uast.Role{uast.Statement, uast.DoWhile}},
+		{InternalType: "Try", Roles: []uast.Role{uast.Statement, uast.Try}},
+	}}
+
+	var result []*NPathData
+	require.NotPanics(func() { result = NPathComplexity(n) })
+	require.Len(result, 1)
+	require.Equal(2*2*2*1, result[0].Complexity)
+
+	var warnings []string
+	for _, w := range result[0].Warnings {
+		warnings = append(warnings, w.String())
+	}
+	require.Equal([]string{
+		"if without Then at L12",
+		"while without Condition",
+		"while without Body",
+		"do-while without Condition",
+		"do-while without Body",
+		"try without Body",
+	}, warnings)
+	require.Equal(Span{12, 3, 120, 12, 3, 120}, result[0].Warnings[0].Span)
+}
+
+// randomTree returns a random UAST made of the roles and internal types the
+// NPath visitors look for, in any combination.
+func randomTree(r *rand.Rand, depth int) *uast.Node {
+	roles := []uast.Role{
+		uast.Statement, uast.Expression, uast.If, uast.Then, uast.Else, uast.Condition,
+		uast.While, uast.DoWhile, uast.For, uast.Body, uast.Switch, uast.Case, uast.Default,
+		uast.Return, uast.Try, uast.Catch, uast.Finally, uast.Function, uast.Declaration,
+		uast.Name, uast.Argument, uast.Anonymous, uast.Type, uast.Package, uast.Module,
+		uast.Identifier, uast.Operator, uast.Boolean, uast.And, uast.Or,
+	}
+	types := []string{"node", "ConditionalExpression", "IfExp", "LambdaExpression", "FuncLit"}
+
+	n := &uast.Node{InternalType: types[r.Intn(len(types))]}
+	for i := r.Intn(5); i > 0; i-- {
+		n.Roles = append(n.Roles, roles[r.Intn(len(roles))])
+	}
+	if r.Intn(3) == 0 {
+		n.Token = "token"
+	}
+	if r.Intn(4) == 0 {
+		line := uint32(r.Intn(100))
+		n.StartPosition = &uast.Position{Line: line, Col: 1}
+	}
+	if depth > 0 {
+		for i := r.Intn(5); i > 0; i-- {
+			n.Children = append(n.Children, randomTree(r, depth-1))
+		}
+	}
+	return n
+}
+
+func TestNPathRandomTrees(t *testing.T) {
+	require := require.New(t)
+
+	r := rand.New(rand.NewSource(42))
+	for i := 0; i < 2000; i++ {
+		n := randomTree(r, 6)
+		for _, np := range []NPath{{}, {ExcludeNested: true}} {
+			var result []*NPathData
+			require.NotPanics(func() { result = np.complexity(n) }, "tree %d", i)
+			for _, f := range result {
+				require.True(f.Complexity >= 1, "tree %d: complexity of %s is %d", i, f.Name, f.Complexity)
+			}
+		}
+	}
+}
+
+func TestNPathSwitchFallThrough(t *testing.T) {
+	require := require.New(t)
+
+	at := func(line uint32, n *uast.Node) *uast.Node {
+		n.StartPosition = &uast.Position{Offset: line * 10, Line: line, Col: 1}
+		return n
+	}
+	label := func(line uint32) *uast.Node {
+		return at(line, &uast.Node{InternalType: "Case", Roles: []uast.Role{uast.Statement, uast.Switch, uast.Case}})
+	}
+	ifStmt := func(line uint32) *uast.Node {
+		return at(line, &uast.Node{InternalType: "If", Roles: []uast.Role{uast.Statement, uast.If}, Children: []*uast.Node{
+			{InternalType: "Condition", Roles: []uast.Role{uast.If, uast.Condition}},
+			{InternalType: "Then", Roles: []uast.Role{uast.If, uast.Then}},
+		}})
+	}
+	jump := func(line uint32, role uast.Role) *uast.Node {
+		return at(line, &uast.Node{InternalType: "Jump", Roles: []uast.Role{uast.Statement, role}, Children: []*uast.Node{
+			{InternalType: "Label", Roles: []uast.Role{uast.Identifier}, Token: "label"},
+		}})
+	}
+
+	/*
+		switch (a && b) {
+		case 1:
+		case 2:
+			if (c) {}
+			if (d) {}
+			break;
+		case 3:
+			if (e) {}
+			continue label;
+		default:
+			goto label;
+		}
+		Npath = 1 (bool_comp) + 1 + 2 * 2 + 2 + 1
+	*/
+	expression := at(1, &uast.Node{InternalType: "Expression", Roles: []uast.Role{uast.Expression, uast.Switch}, Children: []*uast.Node{
+		{InternalType: "bool_and", Roles: []uast.Role{uast.Operator, uast.Boolean, uast.And}},
+	}})
+	n := &uast.Node{InternalType: "Function declaration body", Roles: []uast.Role{uast.Function, uast.Body}, Children: []*uast.Node{
+		{InternalType: "Switch", Roles: []uast.Role{uast.Statement, uast.Switch}, Children: []*uast.Node{
+			// out of order, as some drivers group the labels
+			expression,
+			label(2),
+			label(3),
+			label(7),
+			at(10, &uast.Node{InternalType: "Default", Roles: []uast.Role{uast.Statement, uast.Switch, uast.Default}}),
+			ifStmt(4),
+			ifStmt(5),
+			jump(6, uast.Break),
+			ifStmt(8),
+			jump(9, uast.Continue),
+			jump(11, uast.Goto),
+		}},
+	}}
+	require.Equal(9, NPathComplexity(n)[0].Complexity)
+
+	// a switch without labels
+	n = &uast.Node{InternalType: "Function declaration body", Roles: []uast.Role{uast.Function, uast.Body}, Children: []*uast.Node{
+		{InternalType: "Switch", Roles: []uast.Role{uast.Statement, uast.Switch}, Children: []*uast.Node{expression}},
+	}}
+	require.Equal(2, NPathComplexity(n)[0].Complexity)
+}
+
+func TestNPathJumps(t *testing.T) {
+	require := require.New(t)
+
+	file, err := os.Open("fixtures/npath/jumps.java.json")
+	require.NoError(err)
+	defer file.Close()
+
+	res := &protocol.ParseResponse{}
+	require.NoError(json.NewDecoder(bufio.NewReader(file)).Decode(res))
+
+	var names []string
+	var result []int
+	for _, v := range NPathComplexity(res.UAST) {
+		names = append(names, v.Name)
+		result = append(result, v.Complexity)
+	}
+
+	// The expected values were computed by hand following the source of the
+	// PMD 5.7 NPathComplexityRule, not by running PMD: jumps count as 1 and
+	// each case range is the product of its statements. The fixture is
+	// hand-written, see fixtures/README.md.
+	require.Equal([]string{"Code.find(int[],int)", "Code.days(int,boolean)", "Code.countdown(int)"}, names)
+	require.Equal([]int{5, 9, 3}, result)
+}
@@ -0,0 +1,368 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"gopkg.in/bblfsh/sdk.v1/uast"
+)
+
+// OOMetrics computes the Chidamber & Kemerer metrics of the types, see:
+// https://en.wikipedia.org/wiki/Programming_complexity#Chidamber_and_Kemerer_metrics
+//
+// The types are the nodes with the Type and Declaration roles, and their
+// members are their children: the function declarations are the methods,
+// the ones named as the type are the constructors, and the variable
+// declarations are the fields.
+//
+// * WMC, weighted methods per class, is the sum of the cyclomatic complexity
+// of the methods. The ones without a body, like abstract methods, add one.
+// * DIT, depth of inheritance tree, is the number of ancestors of the type,
+// its Base type and their own. A base type which is not analyzed counts as
+// one, as if it had no ancestors.
+// * NOC, number of children, is the number of types with the type as Base.
+// * CBO, coupling between objects, is the number of other types the type
+// refers to by their name, like the types of its fields, arguments and the
+// objects it creates, or which refer to it.
+// * RFC, response for class, is the number of methods of the type plus the
+// number of other methods it calls.
+// * LCOM4, lack of cohesion of methods, is the number of groups of methods
+// using the same fields or calling each other. The constructors are not
+// counted since they usually initialize all the fields.
+//
+// The metrics depending on other types only use the types in the same node,
+// unless the results are aggregated with the ones of the other analyzed
+// nodes. The types are matched by their unqualified names and the methods by
+// their names, so the overloads are a single method, and the fields are used
+// by the identifiers with their names.
+type OOMetrics struct {
+	// Profile has the rules of the cyclomatic complexity, the default
+	// profile is used if it's nil.
+	Profile *CyclomaticProfile
+}
+
+// TypeData is the object oriented metrics of a type.
+type TypeData struct {
+	Name string `json:"name"`
+	// Base is the name of the type it inherits from, empty if none.
+	Base  string `json:"base,omitempty"`
+	WMC   int    `json:"wmc"`
+	DIT   int    `json:"dit"`
+	NOC   int    `json:"noc"`
+	CBO   int    `json:"cbo"`
+	RFC   int    `json:"rfc"`
+	LCOM4 int    `json:"lcom4"`
+	Span
+
+	// uses are the unqualified names of the other types it refers to.
+	uses map[string]bool
+}
+
+func (td *TypeData) String() string {
+	return fmt.Sprintf("TypeName:%s, WMC:%d, DIT:%d, NOC:%d, CBO:%d, RFC:%d, LCOM4:%d, Position:%s\n",
+		td.Name, td.WMC, td.DIT, td.NOC, td.CBO, td.RFC, td.LCOM4, td.Span)
+}
+
+// OOResult is the result of the OOMetrics tool.
+type OOResult struct {
+	Types []*TypeData `json:"types"`
+}
+
+// Analyze returns an *OOResult with the metrics of every type in the node.
+func (o OOMetrics) Analyze(ctx context.Context, n *uast.Node) (Result, error) {
+	profile := CyclomaticComplexity{Profile: o.Profile}.profile()
+
+	result := &OOResult{}
+	for _, t := range typeDeclarations(n) {
+		data := &TypeData{
+			Name: t.name,
+			Base: baseName(t.decl),
+			Span: NodeSpan(t.decl),
+			uses: typeUses(t.decl),
+		}
+		delete(data.uses, unqualified(t.name))
+
+		members := typeMembers(t.decl)
+		for _, method := range members.methods {
+			if body := functionBody(method); body != nil {
+				data.WMC += profile.complexity(body)
+			} else {
+				data.WMC++
+			}
+		}
+		data.RFC = responseForType(members)
+		data.LCOM4 = lackOfCohesion(members, unqualified(t.name))
+		result.Types = append(result.Types, data)
+	}
+
+	aggregateTypes(result.Types)
+	return result, nil
+}
+
+// Aggregate updates the results of the analyzed nodes with the inheritance
+// and coupling metrics depending on the types of all of them.
+func (o OOMetrics) Aggregate(ctx context.Context, results []Result) error {
+	var types []*TypeData
+	for _, r := range results {
+		if r, ok := r.(*OOResult); ok {
+			types = append(types, r.Types...)
+		}
+	}
+	aggregateTypes(types)
+	return nil
+}
+
+// aggregateTypes computes the DIT, NOC and CBO of the types, which depend on
+// each other.
+func aggregateTypes(types []*TypeData) {
+	byName := make(map[string]*TypeData)
+	for _, t := range types {
+		if _, ok := byName[unqualified(t.Name)]; !ok {
+			byName[unqualified(t.Name)] = t
+		}
+	}
+
+	usedBy := make(map[string]map[string]bool)
+	for _, t := range types {
+		t.NOC = 0
+		for used := range t.uses {
+			if usedBy[used] == nil {
+				usedBy[used] = make(map[string]bool)
+			}
+			usedBy[used][unqualified(t.Name)] = true
+		}
+	}
+
+	for _, t := range types {
+		t.DIT = inheritanceDepth(t, byName)
+		if base, ok := byName[unqualified(t.Base)]; ok && t.Base != "" {
+			base.NOC++
+		}
+
+		name := unqualified(t.Name)
+		couplings := make(map[string]bool)
+		for used := range t.uses {
+			couplings[used] = true
+		}
+		for user := range usedBy[name] {
+			if user != name {
+				couplings[user] = true
+			}
+		}
+		t.CBO = len(couplings)
+	}
+}
+
+// inheritanceDepth returns the number of ancestors of the type, stopping at
+// the ones not analyzed or if they inherit from each other.
+func inheritanceDepth(t *TypeData, byName map[string]*TypeData) int {
+	visited := map[*TypeData]bool{t: true}
+	var depth int
+	for t.Base != "" {
+		depth++
+		base, ok := byName[unqualified(t.Base)]
+		if !ok || visited[base] {
+			break
+		}
+		visited[base] = true
+		t = base
+	}
+	return depth
+}
+
+// typeDeclaration is a type found in a UAST.
+type typeDeclaration struct {
+	// name is the qualified name of the type, like the names of the
+	// functions.
+	name string
+	decl *uast.Node
+}
+
+// typeDeclarations returns the types declared under n, in the order they
+// appear, named like the functions.
+func typeDeclarations(n *uast.Node) []*typeDeclaration {
+	var types []*typeDeclaration
+	walkDeclarations(n, scope{}, func(decl *uast.Node, name string) {
+		if isTypeDeclaration(decl) {
+			types = append(types, &typeDeclaration{name: name, decl: decl})
+		}
+	})
+	return types
+}
+
+// baseName returns the name of the type a type declaration inherits from,
+// its child with the Base role.
+func baseName(decl *uast.Node) string {
+	for _, base := range childrenOfRoles(decl, []uast.Role{uast.Base}, nil) {
+		if name := joinedTokens(base); name != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+// typeUses returns the unqualified names of the types referred to under
+// decl, but the nested type declarations, the primitive types and the
+// visibility modifiers, which some drivers annotate as types. The composite
+// types, like generics or arrays, refer to their components.
+func typeUses(decl *uast.Node) map[string]bool {
+	uses := make(map[string]bool)
+	var collect func(n *uast.Node)
+	collect = func(n *uast.Node) {
+		for _, child := range n.Children {
+			switch {
+			case isTypeDeclaration(child):
+			case containsRoles(child, []uast.Role{uast.Type}, []uast.Role{uast.Primitive, uast.Declaration, uast.Visibility}) &&
+				deepCountChildrenOfRoles(child, []uast.Role{uast.Type}, nil) == 0:
+				if name := joinedTokens(child); name != "" {
+					uses[unqualified(name)] = true
+				}
+			default:
+				collect(child)
+			}
+		}
+	}
+	collect(decl)
+	return uses
+}
+
+// members are the methods and fields declared in a type.
+type members struct {
+	methods []*uast.Node
+	fields  map[string]bool
+}
+
+func typeMembers(decl *uast.Node) *members {
+	m := &members{fields: make(map[string]bool)}
+	for _, child := range decl.Children {
+		switch {
+		case containsRoles(child, []uast.Role{uast.Function, uast.Declaration}, []uast.Role{uast.Argument}):
+			m.methods = append(m.methods, child)
+		case containsRoles(child, []uast.Role{uast.Declaration, uast.Variable}, []uast.Role{uast.Statement}):
+			// the variables of a declaration, like "int a, b", or the
+			// declaration itself
+			variables := childrenOfRoles(child, []uast.Role{uast.Declaration, uast.Variable}, nil)
+			if len(variables) == 0 {
+				variables = []*uast.Node{child}
+			}
+			for _, v := range variables {
+				if name := declarationName(v); name != "" {
+					m.fields[name] = true
+				}
+			}
+		}
+	}
+	return m
+}
+
+// responseForType returns the number of methods of the type plus the number
+// of other methods they call.
+func responseForType(m *members) int {
+	response := make(map[string]bool)
+	for _, method := range m.methods {
+		response[functionName(method)] = true
+	}
+	for _, method := range m.methods {
+		if body := functionBody(method); body != nil {
+			for _, call := range callsUnder(body) {
+				if name := calledName(call); name != "" {
+					response[name] = true
+				}
+			}
+		}
+	}
+	return len(response)
+}
+
+// lackOfCohesion returns the number of groups of methods of a type, but its
+// constructors, connected by the fields they use and the calls between them.
+func lackOfCohesion(m *members, typeName string) int {
+	var methods []*uast.Node
+	for _, method := range m.methods {
+		if functionBody(method) != nil && functionName(method) != typeName {
+			methods = append(methods, method)
+		}
+	}
+
+	// the groups as a union-find of the methods
+	group := make([]int, len(methods))
+	for i := range group {
+		group[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if group[i] != i {
+			group[i] = find(group[i])
+		}
+		return group[i]
+	}
+	// owners are the first method using every field or with every name
+	owners := make(map[string]int)
+	join := func(key string, i int) {
+		if owner, ok := owners[key]; ok {
+			group[find(i)] = find(owner)
+		} else {
+			owners[key] = i
+		}
+	}
+
+	for i, method := range methods {
+		join("()"+functionName(method), i)
+	}
+	for i, method := range methods {
+		body := functionBody(method)
+		for _, id := range deepChildrenOfRoles(body, []uast.Role{uast.Identifier}, nil) {
+			if m.fields[id.Token] {
+				join(id.Token, i)
+			}
+		}
+		for _, call := range callsUnder(body) {
+			if name := calledName(call); name != "" && !strings.Contains(name, ".") {
+				if _, ok := owners["()"+name]; ok {
+					join("()"+name, i)
+				}
+			}
+		}
+	}
+
+	var groups int
+	for i := range methods {
+		if find(i) == i {
+			groups++
+		}
+	}
+	return groups
+}
+
+// callsUnder returns the calls under n, which have their callee as a child.
+func callsUnder(n *uast.Node) []*uast.Node {
+	var calls []*uast.Node
+	for _, c := range deepChildrenOfRoles(n, []uast.Role{uast.Call}, nil) {
+		if halsteadCall.matches(c) {
+			calls = append(calls, c)
+		}
+	}
+	return calls
+}
+
+// calledName returns the name of the method called, qualified by the object
+// it's called on unless it's the same object.
+func calledName(call *uast.Node) string {
+	callees := childrenOfRoles(call, []uast.Role{uast.Call, uast.Callee}, nil)
+	if len(callees) == 0 {
+		return ""
+	}
+	name := joinedTokens(callees[0])
+	for _, receiver := range childrenOfRoles(call, []uast.Role{uast.Call, uast.Receiver}, nil) {
+		if r := joinedTokens(receiver); r != "" && r != "this" && !containsRoles(receiver, []uast.Role{uast.This}, nil) {
+			return r + "." + name
+		}
+	}
+	return name
+}
+
+// unqualified returns the last part of a qualified name.
+func unqualified(name string) string {
+	return name[strings.LastIndex(name, ".")+1:]
+}
@@ -0,0 +1,64 @@
+package tools
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// ooMetrics returns the metrics of the types as WMC, DIT, NOC, CBO, RFC and
+// LCOM4 by type name.
+func ooMetrics(results ...Result) map[string][]int {
+	metrics := make(map[string][]int)
+	for _, r := range results {
+		for _, t := range r.(*OOResult).Types {
+			metrics[t.Name] = []int{t.WMC, t.DIT, t.NOC, t.CBO, t.RFC, t.LCOM4}
+		}
+	}
+	return metrics
+}
+
+func TestOOMetricsFixtures(t *testing.T) {
+	require := require.New(t)
+
+	// the fixtures are hand-written, see fixtures/README.md
+	o := OOMetrics{}
+	circle, err := o.Analyze(context.Background(), fixtureUAST(t, "fixtures/oo/Circle.java.json"))
+	require.NoError(err)
+	shape, err := o.Analyze(context.Background(), fixtureUAST(t, "fixtures/oo/Shape.java.json"))
+	require.NoError(err)
+
+	require.Equal("Shape", circle.(*OOResult).Types[0].Base)
+	// on their own, Shape is not known as the base of Circle nor coupled to it
+	require.Equal(map[string][]int{
+		"shapes.Circle": {5, 1, 1, 4, 5, 2},
+		"shapes.Ring":   {2, 2, 0, 1, 2, 1},
+	}, ooMetrics(circle))
+	require.Equal(map[string][]int{
+		"shapes.Shape": {4, 0, 0, 1, 4, 2},
+	}, ooMetrics(shape))
+
+	require.NoError(o.Aggregate(context.Background(), []Result{circle, shape}))
+	require.Equal(map[string][]int{
+		"shapes.Circle": {5, 1, 1, 4, 5, 2},
+		"shapes.Ring":   {2, 2, 0, 1, 2, 1},
+		"shapes.Shape":  {4, 0, 1, 2, 4, 2},
+	}, ooMetrics(circle, shape))
+
+	// aggregating again doesn't count anything twice
+	require.NoError(o.Aggregate(context.Background(), []Result{circle, shape}))
+	require.Equal([]int{4, 0, 1, 2, 4, 2}, ooMetrics(shape)["shapes.Shape"])
+}
+
+func TestOOMetricsInheritanceCycle(t *testing.T) {
+	require := require.New(t)
+
+	a := &TypeData{Name: "p.A", Base: "B"}
+	b := &TypeData{Name: "p.B", Base: "p.A"}
+	c := &TypeData{Name: "p.C", Base: "A"}
+	aggregateTypes([]*TypeData{a, b, c})
+
+	require.Equal([]int{2, 2, 3}, []int{a.DIT, b.DIT, c.DIT})
+	require.Equal([]int{2, 1, 0}, []int{a.NOC, b.NOC, c.NOC})
+}
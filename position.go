@@ -0,0 +1,39 @@
+package tools
+
+import (
+	"fmt"
+
+	"github.com/bblfsh/tools/internal/uastutil"
+	"gopkg.in/bblfsh/sdk.v1/uast"
+)
+
+// Span is the region of the source code covered by a node. Lines and
+// columns are 1-based, offsets are 0-based byte offsets. A zero line means
+// the position is unknown.
+type Span struct {
+	StartLine   uint32 `json:"start_line"`
+	StartCol    uint32 `json:"start_col"`
+	StartOffset uint32 `json:"start_offset"`
+	EndLine     uint32 `json:"end_line"`
+	EndCol      uint32 `json:"end_col"`
+	EndOffset   uint32 `json:"end_offset"`
+}
+
+func (s Span) String() string {
+	return fmt.Sprintf("%d:%d-%d:%d", s.StartLine, s.StartCol, s.EndLine, s.EndCol)
+}
+
+// NodeSpan returns the span of the node. When the node has no start or end
+// position, the ones of its nearest descendants having them are used.
+func NodeSpan(n *uast.Node) Span {
+	var s Span
+	if start := uastutil.StartPosition(n); start != nil {
+		s.StartLine, s.StartCol, s.StartOffset = start.Line, start.Col, start.Offset
+	}
+	if end := uastutil.EndPosition(n); end != nil {
+		s.EndLine, s.EndCol, s.EndOffset = end.Line, end.Col, end.Offset
+	} else {
+		s.EndLine, s.EndCol, s.EndOffset = s.StartLine, s.StartCol, s.StartOffset
+	}
+	return s
+}
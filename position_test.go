@@ -0,0 +1,86 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/bblfsh/sdk.v1/uast"
+)
+
+func TestNodeSpan(t *testing.T) {
+	require := require.New(t)
+
+	n := &uast.Node{InternalType: "node",
+		StartPosition: &uast.Position{Offset: 10, Line: 2, Col: 3},
+		EndPosition:   &uast.Position{Offset: 20, Line: 3, Col: 4},
+	}
+	require.Equal(Span{2, 3, 10, 3, 4, 20}, NodeSpan(n))
+
+	// without positions, the nearest descendants with positions are used
+	n = &uast.Node{InternalType: "block", Children: []*uast.Node{
+		{InternalType: "stmt", Children: []*uast.Node{
+			{InternalType: "token", StartPosition: &uast.Position{Offset: 30, Line: 4, Col: 1}},
+		}},
+		{InternalType: "stmt",
+			StartPosition: &uast.Position{Offset: 15, Line: 3, Col: 5},
+			EndPosition:   &uast.Position{Offset: 25, Line: 3, Col: 15},
+			Children: []*uast.Node{
+				{InternalType: "token", StartPosition: &uast.Position{Offset: 12, Line: 3, Col: 2}},
+			},
+		},
+		{InternalType: "stmt", Children: []*uast.Node{
+			{InternalType: "token", EndPosition: &uast.Position{Offset: 40, Line: 5, Col: 6}},
+		}},
+	}}
+	require.Equal(Span{3, 5, 15, 5, 6, 40}, NodeSpan(n))
+
+	// without positions at all
+	require.Equal(Span{}, NodeSpan(&uast.Node{InternalType: "empty"}))
+}
+
+func TestNodeSpanWithoutOffsets(t *testing.T) {
+	require := require.New(t)
+
+	// the positions have lines and columns only, so the first and last ones
+	// are told by them
+	n := &uast.Node{InternalType: "block", Children: []*uast.Node{
+		{InternalType: "stmt",
+			StartPosition: &uast.Position{Line: 3, Col: 5},
+			EndPosition:   &uast.Position{Line: 3, Col: 15},
+		},
+		{InternalType: "stmt",
+			StartPosition: &uast.Position{Line: 2, Col: 1},
+			EndPosition:   &uast.Position{Line: 4, Col: 2},
+		},
+		{InternalType: "stmt",
+			StartPosition: &uast.Position{Line: 2, Col: 3},
+			EndPosition:   &uast.Position{Line: 4, Col: 1},
+		},
+	}}
+	require.Equal(Span{2, 1, 0, 4, 2, 0}, NodeSpan(n))
+
+	// the same fixture with and without offsets
+	withOffsets := functions(fixtureUAST(t, "fixtures/npath/someFuncs.java.json"))
+	n = fixtureUAST(t, "fixtures/npath/someFuncs.java.json")
+	clearOffsets(n)
+	withoutOffsets := functions(n)
+	require.Len(withoutOffsets, len(withOffsets))
+	for i, f := range withOffsets {
+		expected := f.span()
+		expected.StartOffset, expected.EndOffset = 0, 0
+		require.Equal(expected, withoutOffsets[i].span(), f.name)
+	}
+}
+
+// clearOffsets sets to zero the offsets of the positions of n and its
+// descendants, as the drivers filling only the lines and columns.
+func clearOffsets(n *uast.Node) {
+	for _, p := range []*uast.Position{n.StartPosition, n.EndPosition} {
+		if p != nil {
+			p.Offset = 0
+		}
+	}
+	for _, child := range n.Children {
+		clearOffsets(child)
+	}
+}
@@ -0,0 +1,108 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+
+	"gopkg.in/bblfsh/sdk.v1/uast"
+)
+
+type Signature struct{}
+
+// SignatureData is the size of the signature and the body of a function.
+type SignatureData struct {
+	Name      string `json:"name"`
+	Arguments int    `json:"arguments"`
+	Returns   int    `json:"returns"`
+	// Statements are the statements of the body, but the blocks grouping
+	// them.
+	Statements int `json:"statements"`
+	// Lines are the lines spanned by the function, as told by the positions
+	// of its nodes.
+	Lines int `json:"lines"`
+	Span
+}
+
+// SignatureResult is the result of the Signature tool.
+type SignatureResult struct {
+	Functions []*SignatureData `json:"functions"`
+}
+
+// Analyze returns a *SignatureResult with the signature metrics of every
+// function in the node.
+func (s Signature) Analyze(ctx context.Context, n *uast.Node) (Result, error) {
+	return &SignatureResult{Functions: SignatureMetrics(n)}, nil
+}
+
+func (sd *SignatureData) String() string {
+	return fmt.Sprintf("FuncName:%s, Arguments:%d, Returns:%d, Statements:%d, Lines:%d, Position:%s\n",
+		sd.Name, sd.Arguments, sd.Returns, sd.Statements, sd.Lines, sd.Span)
+}
+
+// SignatureMetrics computes the number of arguments, return statements,
+// statements and lines of the functions in a *uast.Node.
+//
+// The arguments are the nodes with the Argument role in the declaration,
+// outside of its body, but the nodes grouping them and the arguments of the
+// calls. A variadic argument counts as one. The returns of the nested
+// functions are not counted for the ones declaring them, while their
+// statements and lines are, as in LOC.
+//
+// The functions are found as in NPathComplexity.
+func SignatureMetrics(n *uast.Node) []*SignatureData {
+	var result []*SignatureData
+	for _, function := range functions(n) {
+		data := &SignatureData{
+			Name:       function.name,
+			Returns:    countReturns(function.body),
+			Statements: countStatements(function.body),
+			Span:       function.span(),
+		}
+		if function.decl != nil {
+			data.Arguments = countArguments(function.decl, function.body)
+		}
+		if data.StartLine > 0 && data.EndLine >= data.StartLine {
+			data.Lines = int(data.EndLine-data.StartLine) + 1
+		}
+		result = append(result, data)
+	}
+	return result
+}
+
+// countArguments returns the number of arguments declared under n, without
+// looking into the body nor the nested functions.
+func countArguments(n, body *uast.Node) int {
+	var count int
+	for _, child := range n.Children {
+		switch {
+		case child == body, isFunction(child), containsRoles(child, []uast.Role{uast.Function, uast.Body}, nil):
+		case containsRoles(child, []uast.Role{uast.Argument}, []uast.Role{uast.Call}):
+			// an argument with arguments is a list of them, like the
+			// arguments of python
+			if nested := countArguments(child, body); nested > 0 {
+				count += nested
+			} else {
+				count++
+			}
+		default:
+			count += countArguments(child, body)
+		}
+	}
+	return count
+}
+
+// countReturns returns the number of return statements under n, without
+// looking into the nested functions.
+func countReturns(n *uast.Node) int {
+	var count int
+	for _, child := range n.Children {
+		switch {
+		case isFunction(child):
+		case containsRoles(child, []uast.Role{uast.Statement, uast.Return}, nil):
+			count += 1 + countReturns(child)
+		default:
+			count += countReturns(child)
+		}
+	}
+	return count
+}
@@ -0,0 +1,47 @@
+package tools
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignatureFixture(t *testing.T) {
+	require := require.New(t)
+
+	result, err := Signature{}.Analyze(context.Background(), fixtureUAST(t, "fixtures/npath/cognitive.java.json"))
+	require.NoError(err)
+
+	var got [][]int
+	for _, f := range result.(*SignatureResult).Functions {
+		got = append(got, []int{f.Arguments, f.Returns, f.Statements, f.Lines})
+	}
+	// the argument of the recursive call of fact is not counted
+	require.Equal([][]int{{1, 1, 8, 12}, {1, 3, 7, 8}, {1, 3, 5, 7}, {1, 2, 5, 9}}, got)
+}
+
+func TestSignatureArguments(t *testing.T) {
+	cases := []struct {
+		file      string
+		arguments []int
+		returns   []int
+	}{
+		// the variadic argument of printMax counts as one, reverse has none
+		{"fixtures/npath/someFuncs.java.json", []int{2, 1, 0, 1, 1, 1}, []int{1, 1, 0, 3, 0, 0}},
+		// the arguments of python are grouped in a node
+		{"fixtures/npath/ternary.py.json", []int{2, 1, 1}, []int{1, 1, 2}},
+		// the lambda made of an expression has no return statement
+		{"fixtures/npath/ternary.js.json", []int{2, 1, 1}, []int{1, 1, 0}},
+	}
+
+	for _, c := range cases {
+		var arguments, returns []int
+		for _, f := range SignatureMetrics(fixtureUAST(t, c.file)) {
+			arguments = append(arguments, f.Arguments)
+			returns = append(returns, f.Returns)
+		}
+		require.Equal(t, c.arguments, arguments, c.file)
+		require.Equal(t, c.returns, returns, c.file)
+	}
+}
@@ -1,9 +1,18 @@
 package tools
 
-import "gopkg.in/bblfsh/sdk.v1/uast"
+import (
+	"context"
+
+	"gopkg.in/bblfsh/sdk.v1/uast"
+)
 
 type Tokenizer struct{}
 
+// TokenizerResult is the result of the Tokenizer tool.
+type TokenizerResult struct {
+	Tokens []string `json:"tokens"`
+}
+
 func (t Tokenizer) Exec(node *uast.Node) error {
 	for _, token := range Tokens(node) {
 		print(token)
@@ -11,6 +20,11 @@ func (t Tokenizer) Exec(node *uast.Node) error {
 	return nil
 }
 
+// Analyze returns a *TokenizerResult with the tokens of the node.
+func (t Tokenizer) Analyze(ctx context.Context, node *uast.Node) (Result, error) {
+	return &TokenizerResult{Tokens: Tokens(node)}, nil
+}
+
 // Tokens returns a slice of tokens contained in the node.
 func Tokens(n *uast.Node) []string {
 	var tokens []string
@@ -1,6 +1,10 @@
 package tools
 
-import "gopkg.in/bblfsh/sdk.v1/uast"
+import (
+	"context"
+
+	"gopkg.in/bblfsh/sdk.v1/uast"
+)
 
 // Tooler is an interface which can be implemented by any supported tool.
 // When implemented, the Exec method will be called with a UAST root node.
@@ -9,3 +13,33 @@ type Tooler interface {
 	// to the command handler
 	Exec(*uast.Node) error
 }
+
+// Analyzer is an interface which can be implemented by the tools that return
+// their results instead of printing them, so they can be used as a library.
+type Analyzer interface {
+	// Analyze will be called with a UAST root node and returns the result of
+	// the tool. The concrete type of the result depends on the tool.
+	Analyze(context.Context, *uast.Node) (Result, error)
+}
+
+// SourceAnalyzer is an Analyzer which can also use the source code the UAST
+// was parsed from, for the metrics that can't be computed from the UAST
+// alone, like the blank lines.
+type SourceAnalyzer interface {
+	Analyzer
+	// AnalyzeSource is like Analyze, with the source code of the node.
+	AnalyzeSource(ctx context.Context, n *uast.Node, source string) (Result, error)
+}
+
+// Result is the value returned by an Analyzer. Every tool documents the
+// concrete type of its results.
+type Result interface{}
+
+// Aggregator is an Analyzer whose results also depend on the other analyzed
+// nodes, like the metrics of the type hierarchies spanning several files.
+type Aggregator interface {
+	Analyzer
+	// Aggregate is called once all the nodes are analyzed, with their
+	// results, and updates them with the metrics depending on all of them.
+	Aggregate(ctx context.Context, results []Result) error
+}
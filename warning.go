@@ -0,0 +1,26 @@
+package tools
+
+import (
+	"fmt"
+
+	"gopkg.in/bblfsh/sdk.v1/uast"
+)
+
+// Warning is a problem found while analyzing a UAST that didn't prevent
+// computing the result, like a node missing an expected child. The result
+// may be less accurate than usual.
+type Warning struct {
+	Message string `json:"message"`
+	Span
+}
+
+func newWarning(n *uast.Node, format string, args ...interface{}) *Warning {
+	return &Warning{Message: fmt.Sprintf(format, args...), Span: NodeSpan(n)}
+}
+
+func (w *Warning) String() string {
+	if w.StartLine > 0 {
+		return fmt.Sprintf("%s at L%d", w.Message, w.StartLine)
+	}
+	return w.Message
+}